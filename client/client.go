@@ -89,17 +89,65 @@ type Client struct {
 	envPfx         string
 	flagOverrides  []string
 	cfg            Cfg
+
+	logger kgo.Logger // non-nil if logging is enabled
+
+	timeoutOverride time.Duration // from a command's --timeout flag, if added
 }
 
 // AsJSON returns whether the output should be dumped as JSON if applicable.
 func (c *Client) AsJSON() bool { return c.asJSON }
 
-// TimeoutMillis is what requests that have timeouts should use.
+const (
+	// minTimeoutMillis and maxTimeoutMillis bound what we consider a
+	// sane timeout_ms; we warn on load if the config is outside them.
+	minTimeoutMillis = 1000
+	maxTimeoutMillis = 5 * 60 * 1000
+)
+
+// TimeoutMillis is what requests that have timeouts should use. If the
+// command has a --timeout flag (see AddTimeoutFlag) and it was set, that is
+// used instead of the config value.
 func (c *Client) TimeoutMillis() int32 {
 	c.loadClientOnce()
+	if c.timeoutOverride > 0 {
+		return durationMillis(c.timeoutOverride)
+	}
 	return c.cfg.TimeoutMillis
 }
 
+// TimeoutMillisAtLeast is TimeoutMillis for requests that are known to need
+// at least floor to complete broker side, such as leader elections. If the
+// config value is below floor, floor is used and a note with why is logged at
+// the info level. A --timeout flag still overrides everything.
+func (c *Client) TimeoutMillisAtLeast(floor time.Duration, why string) int32 {
+	millis := c.TimeoutMillis()
+	if c.timeoutOverride > 0 || millis >= durationMillis(floor) {
+		return millis
+	}
+	if c.logger != nil {
+		c.logger.Log(kgo.LogLevelInfo, "using a request timeout above timeout_ms",
+			"timeout_ms", millis,
+			"timeout", floor,
+			"why", why,
+		)
+	}
+	return durationMillis(floor)
+}
+
+// AddTimeoutFlag adds a --timeout flag to cmd that overrides the timeout
+// returned from TimeoutMillis and TimeoutMillisAtLeast.
+func (c *Client) AddTimeoutFlag(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&c.timeoutOverride, "timeout", 0, "if non-zero, the request timeout to use, overriding timeout_ms and any command specific minimum")
+}
+
+func durationMillis(d time.Duration) int32 {
+	if ms := d.Milliseconds(); ms < math.MaxInt32 {
+		return int32(ms)
+	}
+	return math.MaxInt32
+}
+
 // New returns a new Client with the given config and installs some
 // persistent flags and commands to root.
 func New(root *cobra.Command) *Client {
@@ -107,10 +155,7 @@ func New(root *cobra.Command) *Client {
 		opts: []kgo.Opt{
 			kgo.MetadataMinAge(time.Second),
 		},
-		cfg: Cfg{
-			SeedBrokers:   []string{"localhost:9092"},
-			TimeoutMillis: 5000,
-		},
+		cfg: defaultCfg(),
 	}
 
 	cfgDir, err := os.UserConfigDir()
@@ -143,6 +188,13 @@ func New(root *cobra.Command) *Client {
 	return c
 }
 
+func defaultCfg() Cfg {
+	return Cfg{
+		SeedBrokers:   []string{"localhost:9092"},
+		TimeoutMillis: 5000,
+	}
+}
+
 // AddOpt adds an option to be passed to the eventual new kgo.Client.
 func (c *Client) AddOpt(opt kgo.Opt) {
 	c.opts = append(c.opts, opt)
@@ -187,6 +239,47 @@ func (c *Client) RemakeWithOpts(opts ...kgo.Opt) *kgo.Client {
 	return c.client
 }
 
+// ClientWithOpts returns a new, additional kgo.Client using all buffered
+// options plus opts, for commands that need more than one client at a time.
+// Unlike RemakeWithOpts, the primary client is left open.
+//
+// The primary client is loaded if it has not been already. The returned
+// client must be closed by the caller.
+func (c *Client) ClientWithOpts(opts ...kgo.Opt) (*kgo.Client, error) {
+	c.loadClientOnce()
+	return kgo.NewClient(append(c.opts[:len(c.opts):len(c.opts)], opts...)...)
+}
+
+// ClientForCfgFile returns a new kgo.Client for the cluster described by the
+// config file at path, for commands that talk to a second cluster. Only the
+// file is used: environment and flag config overrides apply to the primary
+// client only. The --as-version and logging flags are shared.
+//
+// The primary client is loaded if it has not been already. The returned
+// client must be closed by the caller.
+func (c *Client) ClientForCfgFile(path string) *kgo.Client {
+	c.loadClientOnce()
+	other := &Client{
+		opts: []kgo.Opt{
+			kgo.MetadataMinAge(time.Second),
+		},
+		asVersion: c.asVersion,
+		cfg:       defaultCfg(),
+	}
+	md, err := toml.DecodeFile(path, &other.cfg)
+	out.MaybeDie(err, "unable to decode config file %q: %v", path, err)
+	if len(md.Undecoded()) > 0 {
+		out.Die("unknown keys in toml cfg %q: %v", path, md.Undecoded())
+	}
+	if c.logger != nil {
+		other.AddOpt(kgo.WithLogger(c.logger))
+	}
+	other.addCfgOpts()
+	cl, err := kgo.NewClient(other.opts...)
+	out.MaybeDie(err, "unable to load client for %q: %v", path, err)
+	return cl
+}
+
 func (c *Client) loadClientOnce() {
 	c.once.Do(func() {
 		c.fillOpts()
@@ -206,10 +299,17 @@ func (c *Client) loadTxnSessOnce() {
 }
 
 func (c *Client) fillOpts() {
-	c.parseCfgFile()        // loads config file if needed
-	c.processOverrides()    // overrides config values just loaded
+	c.parseCfgFile()     // loads config file if needed
+	c.processOverrides() // overrides config values just loaded
+	c.warnTimeout()      // warns if timeout_ms is likely a mistake
+	c.parseLogLevel()    // adds basic logger if necessary
+	c.addCfgOpts()       // adds opts for the final config
+}
+
+// addCfgOpts adds the max versions, SASL, TLS, and seed broker opts for the
+// loaded config.
+func (c *Client) addCfgOpts() {
 	c.maybeAddMaxVersions() // fills MaxVersions if necessary
-	c.parseLogLevel()       // adds basic logger if necessary
 
 	if err := c.maybeAddSASL(); err != nil {
 		out.Die("sasl error: %v", err)
@@ -337,6 +437,20 @@ func (c *Client) processOverrides() {
 	parse(c.flagOverrides)
 }
 
+func (c *Client) warnTimeout() {
+	if c.timeoutOverride > 0 {
+		return
+	}
+	switch ms := c.cfg.TimeoutMillis; {
+	case ms < minTimeoutMillis:
+		fmt.Fprintf(os.Stderr, "WARNING: timeout_ms %d is below %d; brokers may fail requests such as topic creation or leader elections with timeout errors before the operation can complete\n",
+			ms, minTimeoutMillis)
+	case ms > maxTimeoutMillis:
+		fmt.Fprintf(os.Stderr, "WARNING: timeout_ms %d is above %d; requests that brokers cannot complete may block for a long time before failing\n",
+			ms, maxTimeoutMillis)
+	}
+}
+
 func (c *Client) maybeAddMaxVersions() {
 	if c.asVersion != "" {
 		versions := kversion.FromString(c.asVersion)
@@ -522,7 +636,8 @@ func (c *Client) parseLogLevel() {
 		out.MaybeDie(err, "unable to open log-file %q: %v", c.logFile, err)
 		of = f
 	}
-	c.opts = append(c.opts, kgo.WithLogger(kgo.BasicLogger(of, level, nil)))
+	c.logger = kgo.BasicLogger(of, level, nil)
+	c.opts = append(c.opts, kgo.WithLogger(c.logger))
 }
 
 func Strnorm(s string) string {
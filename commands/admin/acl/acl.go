@@ -60,6 +60,7 @@ In requests, a resource type has the following options:
   - CLUSTER
   - TRANSACTIONAL_ID
   - DELEGATION_TOKEN
+  - USER
 
 The resource name for topics would be topic names, for groups, group names, and
 so on. For CLUSTER, the name must be "kafka-cluster". Lastly, Kafka understands
@@ -98,6 +99,8 @@ Operation is what "operation" is allowed. Valid operations are:
   - DESCRIBE_CONFIGS
   - ALTER_CONFIGS
   - IDEMPOTENT_WRITE
+  - CREATE_TOKENS
+  - DESCRIBE_TOKENS
 
 Note that READ, WRITE, DELETE, and ALTER imply DESCRIBE, and ALTER_CONFIGS
 implies DESCRIBE_CONFIGS.
@@ -118,6 +121,8 @@ Different resource types have different potential operations:
 
   - DELEGATION_TOKEN can have DESCRIBE
 
+  - USER can have CREATE_TOKENS and DESCRIBE_TOKENS
+
 Lastly, the permission type specifies whether an entity (user) is allowed to do
 the operation; this is either DENY or ALLOW. Filters can also use ANY, and
 Kafka replies to unknown permissions with UNKNOWN.
@@ -137,6 +142,8 @@ as the principal of the user that created the token.
 		describeCommand(cl),
 		createCommand(cl),
 		deleteCommand(cl),
+		exportCommand(cl),
+		applyCommand(cl),
 	)
 
 	return cmd
@@ -485,6 +492,8 @@ func atoiResourceType(s string) kmsg.ACLResourceType {
 		return 5
 	case "delegationtoken":
 		return 6
+	case "user":
+		return 7
 	default:
 		return 0
 	}
@@ -531,6 +540,10 @@ func atoiOperation(o string) kmsg.ACLOperation {
 		return 11
 	case "idempotentwrite":
 		return 12
+	case "createtokens":
+		return 13
+	case "describetokens":
+		return 14
 	default:
 		return 0
 	}
@@ -0,0 +1,381 @@
+package acl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/twmb/kcl/client"
+	"github.com/twmb/kcl/out"
+)
+
+// exportedACL is one ACL as written by export and read by apply.
+type exportedACL struct {
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	Pattern    string `json:"pattern"`
+	Principal  string `json:"principal"`
+	Host       string `json:"host"`
+	Operation  string `json:"operation"`
+	Permission string `json:"permission"`
+}
+
+// aclKey is a parsed exportedACL, used to compare ACLs across clusters.
+type aclKey struct {
+	typ        kmsg.ACLResourceType
+	name       string
+	pattern    kmsg.ACLResourcePatternType
+	principal  string
+	host       string
+	operation  kmsg.ACLOperation
+	permission kmsg.ACLPermissionType
+}
+
+// key parses an exportedACL. Names are parsed with kmsg's parsers, which
+// understand every name that kmsg's String methods (and thus export) write.
+func (e exportedACL) key() (aclKey, error) {
+	k := aclKey{
+		name:      e.Name,
+		principal: e.Principal,
+		host:      e.Host,
+	}
+	var err error
+	if k.typ, err = kmsg.ParseACLResourceType(e.Type); err != nil || k.typ <= kmsg.ACLResourceTypeAny {
+		return k, fmt.Errorf("invalid resource type %q", e.Type)
+	}
+	if k.pattern, err = kmsg.ParseACLResourcePatternType(e.Pattern); err != nil ||
+		k.pattern != kmsg.ACLResourcePatternTypeLiteral && k.pattern != kmsg.ACLResourcePatternTypePrefixed {
+		return k, fmt.Errorf("invalid resource pattern type %q", e.Pattern)
+	}
+	if k.operation, err = kmsg.ParseACLOperation(e.Operation); err != nil || k.operation <= kmsg.ACLOperationAny {
+		return k, fmt.Errorf("invalid operation %q", e.Operation)
+	}
+	if k.permission, err = kmsg.ParseACLPermissionType(e.Permission); err != nil || k.permission <= kmsg.ACLPermissionTypeAny {
+		return k, fmt.Errorf("invalid permission %q", e.Permission)
+	}
+	switch {
+	case k.principal == "":
+		return k, errors.New("missing principal")
+	case k.host == "":
+		return k, errors.New("missing host")
+	}
+	return k, nil
+}
+
+// describeAll returns every ACL in the cluster.
+func describeAll(cl *kgo.Client) ([]exportedACL, error) {
+	req := kmsg.NewPtrDescribeACLsRequest()
+	req.ResourceType = kmsg.ACLResourceTypeAny
+	req.ResourcePatternType = kmsg.ACLResourcePatternTypeAny
+	req.Operation = kmsg.ACLOperationAny
+	req.PermissionType = kmsg.ACLPermissionTypeAny
+
+	resp, err := req.RequestWith(context.Background(), cl)
+	if err != nil {
+		return nil, err
+	}
+	if err := kerr.ErrorForCode(resp.ErrorCode); err != nil {
+		if resp.ErrorMessage != nil {
+			return nil, fmt.Errorf("%v: %s", err, *resp.ErrorMessage)
+		}
+		return nil, err
+	}
+
+	acls := make([]exportedACL, 0)
+	for _, resource := range resp.Resources {
+		for _, acl := range resource.ACLs {
+			acls = append(acls, exportedACL{
+				Type:       resource.ResourceType.String(),
+				Name:       resource.ResourceName,
+				Pattern:    resource.ResourcePatternType.String(),
+				Principal:  acl.Principal,
+				Host:       acl.Host,
+				Operation:  acl.Operation.String(),
+				Permission: acl.PermissionType.String(),
+			})
+		}
+	}
+	return acls, nil
+}
+
+func exportCommand(cl *client.Client) *cobra.Command {
+	return &cobra.Command{
+		Use:   "export",
+		Short: "Export all ACLs as JSON.",
+		Long: `Export all ACLs in the cluster as JSON (Kafka 0.11.0+).
+
+This prints a JSON array of every ACL in the cluster, one object per ACL. The
+output can be saved and passed to the apply command to create the same ACLs in
+another cluster, or to restore ACLs later.
+
+For more detailed information about ACLs, read kcl acl --help.
+`,
+		Args: cobra.ExactArgs(0),
+		Run: func(_ *cobra.Command, _ []string) {
+			acls, err := describeAll(cl.Client())
+			out.MaybeDie(err, "unable to describe acls: %v", err)
+			out.DumpJSON(acls)
+		},
+	}
+}
+
+// principalRewrite rewrites principals fully matching re to repl.
+type principalRewrite struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// parsePrincipalRewrite parses a PATTERN=REPLACEMENT rewrite. The pattern
+// ends at the first = that is not escaped with a backslash; RE2 accepts \= as
+// a literal =, so the pattern is compiled as is.
+func parsePrincipalRewrite(raw string) (principalRewrite, error) {
+	split := -1
+	for i := 0; i < len(raw) && split < 0; i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '=':
+			split = i
+		}
+	}
+	if split < 0 {
+		return principalRewrite{}, fmt.Errorf("rewrite %q is not PATTERN=REPLACEMENT", raw)
+	}
+	re, err := regexp.Compile("^(?:" + raw[:split] + ")$")
+	if err != nil {
+		return principalRewrite{}, fmt.Errorf("rewrite %q has an invalid pattern: %v", raw, err)
+	}
+	return principalRewrite{re, raw[split+1:]}, nil
+}
+
+// rewritePrincipal returns the principal rewritten by the first matching
+// rewrite, and whether any rewrite matched.
+func rewritePrincipal(rewrites []principalRewrite, principal string) (string, bool) {
+	for _, r := range rewrites {
+		if r.re.MatchString(principal) {
+			return r.re.ReplaceAllString(principal, r.repl), true
+		}
+	}
+	return principal, false
+}
+
+func applyCommand(cl *client.Client) *cobra.Command {
+	var (
+		sourceCfg      string
+		rewriteFlags   []string
+		requireRewrite bool
+		dryRun         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply [FILE]",
+		Short: "Create ACLs from an export, optionally rewriting principals.",
+		Long: `Create ACLs from an export, optionally rewriting principals (Kafka 0.11.0+).
+
+This command reads ACLs in the format written by the export command and
+creates any that do not already exist in the cluster. ACLs are read from FILE,
+from stdin if FILE is "-" or missing, or, with --source-config, directly from
+another cluster: --source-config is a path to a kcl config file describing the
+cluster to export from, which allows copying ACLs between clusters in one
+invocation. Only the file is used for the source cluster; config overrides from
+the environment or -X flags apply to the destination cluster.
+
+When migrating between clusters, principals often change. The
+--rewrite-principal flag rewrites principals as PATTERN=REPLACEMENT, where
+PATTERN is an RE2 regular expression that must match the entire principal and
+REPLACEMENT can reference capture groups with $1 or ${name}. Use ${1} when a
+group reference is followed by a letter, digit, or underscore. If the pattern
+itself needs a literal =, escape it as \=. The flag is repeatable; the first
+pattern that matches a principal is used. Principals that match no pattern are
+applied unchanged, unless --require-rewrite is used, in which case any
+unmatched principal is an error and nothing is created. This is useful to catch
+typos in patterns.
+
+Before creating anything, all ACLs in the destination cluster are described,
+and ACLs that already exist are skipped. The --dry-run flag prints what would
+be created, showing both the rewritten and the source principal, without
+creating anything.
+
+ACLs that cannot be parsed (for example, an operation that kcl does not know)
+are reported and skipped; all other ACLs are still created, and the command
+exits non-zero once done.
+
+For more detailed information about ACLs, read kcl acl --help.
+`,
+		Example: `apply acls.json
+
+apply --source-config old.toml --rewrite-principal 'User:old-(.*)=User:new-$1' --dry-run
+
+apply acls.json --rewrite-principal 'User:CN\=(.*),OU\=eng=User:${1}-eng' --require-rewrite`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			var rewrites []principalRewrite
+			for _, raw := range rewriteFlags {
+				r, err := parsePrincipalRewrite(raw)
+				out.MaybeDie(err, "%v", err)
+				rewrites = append(rewrites, r)
+			}
+
+			var source []exportedACL
+			if sourceCfg != "" {
+				if len(args) > 0 {
+					out.Die("cannot apply from both a file and --source-config")
+				}
+				scl := cl.ClientForCfgFile(sourceCfg)
+				var err error
+				source, err = describeAll(scl)
+				scl.Close()
+				out.MaybeDie(err, "unable to describe acls in the source cluster: %v", err)
+			} else {
+				var r io.Reader = os.Stdin
+				if len(args) > 0 && args[0] != "-" {
+					f, err := os.Open(args[0])
+					out.MaybeDie(err, "unable to open %q: %v", args[0], err)
+					defer f.Close()
+					r = f
+				}
+				dec := json.NewDecoder(r)
+				dec.DisallowUnknownFields()
+				err := dec.Decode(&source)
+				out.MaybeDie(err, "unable to decode acls: %v", err)
+			}
+
+			type application struct {
+				key             aclKey
+				sourcePrincipal string
+				exists          bool
+			}
+			var (
+				apps      []application
+				seen      = make(map[aclKey]bool)
+				unmatched = make(map[string]bool)
+				invalid   int
+			)
+			for i, acl := range source {
+				sourcePrincipal := acl.Principal
+				principal, matched := rewritePrincipal(rewrites, sourcePrincipal)
+				if !matched {
+					unmatched[sourcePrincipal] = true
+				}
+				acl.Principal = principal
+				k, err := acl.key()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "skipping invalid acl at index %d: %v\n", i, err)
+					invalid++
+					continue
+				}
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+				apps = append(apps, application{key: k, sourcePrincipal: sourcePrincipal})
+			}
+			if requireRewrite && len(unmatched) > 0 {
+				var principals []string
+				for p := range unmatched {
+					principals = append(principals, p)
+				}
+				sort.Strings(principals)
+				out.Die("principals matched no rewrite: %s", strings.Join(principals, ", "))
+			}
+
+			existing, err := describeAll(cl.Client())
+			out.MaybeDie(err, "unable to describe acls: %v", err)
+			have := make(map[aclKey]bool)
+			for _, acl := range existing {
+				if k, err := acl.key(); err == nil {
+					have[k] = true
+				}
+			}
+
+			req := kmsg.NewPtrCreateACLsRequest()
+			for i := range apps {
+				app := &apps[i]
+				if app.exists = have[app.key]; app.exists {
+					continue
+				}
+				c := kmsg.NewCreateACLsRequestCreation()
+				c.ResourceType = app.key.typ
+				c.ResourceName = app.key.name
+				c.ResourcePatternType = app.key.pattern
+				c.Principal = app.key.principal
+				c.Host = app.key.host
+				c.Operation = app.key.operation
+				c.PermissionType = app.key.permission
+				req.Creations = append(req.Creations, c)
+			}
+
+			var results []kmsg.CreateACLsResponseResult
+			if !dryRun && len(req.Creations) > 0 {
+				resp, err := req.RequestWith(context.Background(), cl.Client())
+				out.MaybeDie(err, "unable to create acls: %v", err)
+				if cl.AsJSON() {
+					out.ExitJSON(resp)
+				}
+				if len(resp.Results) != len(req.Creations) {
+					fmt.Fprintf(os.Stderr, "Kafka replied with only %d responses to our %d creations! Dumping response as JSON...",
+						len(resp.Results), len(req.Creations))
+					out.ExitJSON(resp)
+				}
+				results = resp.Results
+			}
+
+			tw := out.BeginTabWrite()
+			fmt.Fprintf(tw, "TYPE\tNAME\tPATTERN\tPRINCIPAL\tSOURCE PRINCIPAL\tHOST\tOPERATION\tPERMISSION\tRESULT\tERROR MSG\n")
+			for _, app := range apps {
+				result, errMsg := "EXISTS", ""
+				switch {
+				case app.exists:
+				case dryRun:
+					result = "CREATE"
+				default:
+					r := results[0]
+					results = results[1:]
+					result = "CREATED"
+					if err := kerr.ErrorForCode(r.ErrorCode); err != nil {
+						result = err.Error()
+						if r.ErrorMessage != nil {
+							errMsg = *r.ErrorMessage
+						}
+					}
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					app.key.typ,
+					app.key.name,
+					app.key.pattern,
+					app.key.principal,
+					app.sourcePrincipal,
+					app.key.host,
+					app.key.operation,
+					app.key.permission,
+					result,
+					errMsg,
+				)
+			}
+			tw.Flush()
+
+			if invalid > 0 {
+				out.Die("skipped %d invalid acls", invalid)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&sourceCfg, "source-config", "", "if non-empty, a kcl config file for a cluster to read ACLs from rather than reading an export")
+	cmd.Flags().StringArrayVar(&rewriteFlags, "rewrite-principal", nil, "rewrite principals fully matching an RE2 pattern, PATTERN=REPLACEMENT (e.g. 'User:old-(.*)=User:new-$1'); repeatable, first match wins")
+	cmd.Flags().BoolVar(&requireRewrite, "require-rewrite", false, "fail if any principal matches no --rewrite-principal pattern")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the ACLs that would be created without creating them")
+
+	return cmd
+}
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -56,6 +57,10 @@ func Command(cl *client.Client) *cobra.Command {
 	return cmd
 }
 
+// electLeadersTimeout is the minimum request timeout for leader elections,
+// which can take the controller a while across many partitions.
+const electLeadersTimeout = 30 * time.Second
+
 func electLeaderCommand(cl *client.Client) *cobra.Command {
 	var allPartitions bool
 	var unclean bool
@@ -84,7 +89,7 @@ To avoid accidental triggers, this command requires a --run flag to run.
 			}
 
 			req := &kmsg.ElectLeadersRequest{
-				TimeoutMillis: cl.TimeoutMillis(),
+				TimeoutMillis: cl.TimeoutMillisAtLeast(electLeadersTimeout, "leader elections"),
 			}
 			if unclean {
 				req.ElectionType = 1
@@ -142,6 +147,7 @@ To avoid accidental triggers, this command requires a --run flag to run.
 	cmd.Flags().BoolVar(&allPartitions, "all-partitions", false, "trigger leader election on all topics for all partitions")
 	cmd.Flags().BoolVar(&unclean, "unclean", false, "allow unclean leader election (Kafka 2.4.0+)")
 	cmd.Flags().BoolVar(&run, "run", false, "actually run the command (avoids accidental elections without this flag)")
+	cl.AddTimeoutFlag(cmd)
 
 	return cmd
 }
@@ -263,6 +269,7 @@ delete-records --json-file records.json`,
 	}
 
 	cmd.Flags().StringVar(&jsonFile, "json-file", "", "if non-empty, a json file to read deletions from")
+	cl.AddTimeoutFlag(cmd)
 
 	return cmd
 }
@@ -19,6 +19,7 @@ import (
 func describeCommand(cl *client.Client) *cobra.Command {
 	var verbose bool
 	var readCommitted bool
+	var problemsOnly bool
 
 	// TODO include authorized options (Kafka 2.3.0+)?
 	cmd := &cobra.Command{
@@ -33,14 +34,28 @@ func describeCommand(cl *client.Client) *cobra.Command {
 				out.Die("no groups to describe")
 			}
 
-			if verbose {
+			if verbose || problemsOnly {
 				described := describeGroups(cl, groups)
 				fetchedOffsets := fetchOffsets(cl, groups)
-				listedOffsets := listOffsets(cl, described, readCommitted)
+				// A partition can only be listed once per ListOffsets
+				// request, so we list end and start offsets separately.
+				listedOffsets := listOffsets(cl, described, fetchedOffsets, readCommitted, -1)
+				startOffsets := listOffsets(cl, described, fetchedOffsets, false, -2)
+				// With --committed, the listed end offsets are last
+				// stable offsets, which a read uncommitted consumer
+				// can commit past; whether a commit is ahead is
+				// checked against the high watermark.
+				highWatermarks := listedOffsets
+				if readCommitted {
+					highWatermarks = listOffsets(cl, described, fetchedOffsets, false, -1)
+				}
 				printDescribed(
 					described,
 					fetchedOffsets,
 					listedOffsets,
+					highWatermarks,
+					startOffsets,
+					problemsOnly,
 				)
 				return
 			}
@@ -81,6 +96,7 @@ func describeCommand(cl *client.Client) *cobra.Command {
 
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose printing including client id, host, committed offset, lag, and user data")
 	cmd.Flags().BoolVar(&readCommitted, "committed", false, "if describing verbosely, whether to list only committed offsets as opposed to latest (Kafka 0.11.0+)")
+	cmd.Flags().BoolVar(&problemsOnly, "problems-only", false, "describe verbosely, but only print partitions whose committed offset is ahead of the log end (the high watermark, even with --committed) or behind the log start")
 
 	return cmd
 }
@@ -148,8 +164,10 @@ type offset struct {
 	err error
 }
 
-func fetchOffsets(cl *client.Client, groups []string) map[string]map[int32]offset {
-	fetched := make(map[string]map[int32]offset)
+// fetchOffsets returns the committed offsets for each group, keyed by group,
+// then topic, then partition.
+func fetchOffsets(cl *client.Client, groups []string) map[string]map[string]map[int32]offset {
+	fetched := make(map[string]map[string]map[int32]offset)
 	var failures int
 	for i := range groups {
 		req := kmsg.NewPtrOffsetFetchRequest()
@@ -161,11 +179,13 @@ func fetchOffsets(cl *client.Client, groups []string) map[string]map[int32]offse
 			continue
 		}
 
+		fetchedg := make(map[string]map[int32]offset)
+		fetched[groups[i]] = fetchedg
 		for _, topic := range resp.Topics {
-			fetchedt := fetched[topic.Topic]
+			fetchedt := fetchedg[topic.Topic]
 			if fetchedt == nil {
 				fetchedt = make(map[int32]offset)
-				fetched[topic.Topic] = fetchedt
+				fetchedg[topic.Topic] = fetchedt
 			}
 			for _, partition := range topic.Partitions {
 				fetchedt[partition.Partition] = offset{
@@ -181,20 +201,39 @@ func fetchOffsets(cl *client.Client, groups []string) map[string]map[int32]offse
 	return fetched
 }
 
-func listOffsets(cl *client.Client, described []describedGroup, readCommitted bool) map[string]map[int32]offset {
+// listOffsets lists offsets for all partitions assigned to members in the
+// described groups as well as all partitions with offsets in fetched, which
+// may be nil; timestamp is -1 for end offsets and -2 for start offsets.
+func listOffsets(
+	cl *client.Client,
+	described []describedGroup,
+	fetched map[string]map[string]map[int32]offset,
+	readCommitted bool,
+	timestamp int64,
+) map[string]map[int32]offset {
 	tps := make(map[string]map[int32]struct{})
+	add := func(topic string, partition int32) {
+		if tps[topic] == nil {
+			tps[topic] = make(map[int32]struct{})
+		}
+		tps[topic][partition] = struct{}{}
+	}
 	for _, group := range described {
 		for _, member := range group.Members {
 			for _, topic := range member.MemberAssignment.Topics {
-				if tps[topic.Topic] == nil {
-					tps[topic.Topic] = make(map[int32]struct{})
-				}
 				for _, partition := range topic.Partitions {
-					tps[topic.Topic][partition] = struct{}{}
+					add(topic.Topic, partition)
 				}
 			}
 		}
 	}
+	for _, topics := range fetched {
+		for topic, partitions := range topics {
+			for partition := range partitions {
+				add(topic, partition)
+			}
+		}
+	}
 
 	req := kmsg.NewPtrListOffsetsRequest()
 	if readCommitted {
@@ -206,7 +245,7 @@ func listOffsets(cl *client.Client, described []describedGroup, readCommitted bo
 		for partition := range partitions {
 			reqPartition := kmsg.NewListOffsetsRequestTopicPartition()
 			reqPartition.Partition = partition
-			reqPartition.Timestamp = -1 // latest
+			reqPartition.Timestamp = timestamp
 			reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
 		}
 		req.Topics = append(req.Topics, reqTopic)
@@ -242,12 +281,23 @@ func listOffsets(cl *client.Client, described []describedGroup, readCommitted bo
 	return listed
 }
 
+// lookupOffset returns the offset for a partition in m, or an offset at -1 if
+// the partition is not in m.
+func lookupOffset(m map[string]map[int32]offset, topic string, partition int32) offset {
+	o, exists := m[topic][partition]
+	if !exists {
+		return offset{at: -1}
+	}
+	return o
+}
+
 type describeRow struct {
 	topic         string
 	partition     int32
 	currentOffset string
 	logEndOffset  int64
 	lag           string
+	anomaly       string
 	memberID      string
 	instanceID    *string
 	clientID      string
@@ -257,78 +307,126 @@ type describeRow struct {
 
 func printDescribed(
 	groups []describedGroup,
-	fetched map[string]map[int32]offset,
+	fetched map[string]map[string]map[int32]offset,
 	listed map[string]map[int32]offset,
+	hwms map[string]map[int32]offset,
+	started map[string]map[int32]offset,
+	problemsOnly bool,
 ) {
-	lookup := func(m map[string]map[int32]offset, topic string, partition int32) offset {
-		p := m[topic]
-		if p == nil {
-			return offset{at: -1}
-		}
-		o, exists := p[partition]
-		if !exists {
-			return offset{at: -1}
-		}
-		return o
-	}
-
 	sort.Slice(groups, func(i, j int) bool {
 		return groups[i].Group < groups[j].Group
 	})
 
+	var printed bool
 	for _, group := range groups {
 		var rows []describeRow
-		var useInstanceID, useErr bool
-		for _, member := range group.Members {
-			for _, topic := range member.MemberAssignment.Topics {
-				t := topic.Topic
-				for _, p := range topic.Partitions {
-					committed := lookup(fetched, t, p)
-					end := lookup(listed, t, p)
-
-					row := describeRow{
-						topic:     t,
-						partition: p,
-
-						logEndOffset: end.at,
+		var useInstanceID, useErr, useAnomaly bool
+		committedOffsets := fetched[group.Group]
+		owned := make(map[string]map[int32]bool)
+
+		// addRow adds a row for a partition; member is nil if the
+		// partition has a committed offset but is not assigned to any
+		// member, which is the case for groups that are stopped (as
+		// is typical during restores) or that only commit offsets.
+		addRow := func(t string, p int32, member *describedGroupMember) {
+			committed := lookupOffset(committedOffsets, t, p)
+			end := lookupOffset(listed, t, p)
+			hwm := lookupOffset(hwms, t, p)
+			start := lookupOffset(started, t, p)
+
+			row := describeRow{
+				topic:     t,
+				partition: p,
+
+				logEndOffset: end.at,
+
+				err: committed.err,
+			}
+			if member != nil {
+				row.memberID = member.MemberID
+				row.instanceID = member.InstanceID
+				row.clientID = member.ClientID
+				row.host = member.ClientHost
+			}
+			if row.err == nil {
+				row.err = end.err
+			}
 
-						memberID:   member.MemberID,
-						instanceID: member.InstanceID,
-						clientID:   member.ClientID,
-						host:       member.ClientHost,
-						err:        committed.err,
-					}
-					if row.err == nil {
-						row.err = end.err
-					}
+			useErr = useErr || row.err != nil
+			useInstanceID = useInstanceID || row.instanceID != nil
 
-					useErr = row.err != nil
-					useInstanceID = row.instanceID != nil
+			row.currentOffset = strconv.FormatInt(committed.at, 10)
+			if committed.at == -1 {
+				row.currentOffset = "-"
+			}
 
-					row.currentOffset = strconv.FormatInt(committed.at, 10)
-					if committed.at == -1 {
-						row.currentOffset = "-"
-					}
+			row.lag = strconv.FormatInt(end.at-committed.at, 10)
+			if end.at == 0 {
+				row.lag = "-"
+			} else if committed.at == -1 {
+				row.lag = strconv.FormatInt(end.at, 10)
+			}
 
-					row.lag = strconv.FormatInt(end.at-committed.at, 10)
-					if end.at == 0 {
-						row.lag = "-"
-					} else if committed.at == -1 {
-						row.lag = strconv.FormatInt(end.at, 10)
-					}
+			// After unclean restores, a committed offset can be
+			// past the end of the partition or before the start.
+			// We annotate these rather than print negative or
+			// inflated lag.
+			if committed.at >= 0 && end.err == nil && end.at >= 0 && hwm.err == nil && hwm.at >= 0 {
+				if committed.at > hwm.at {
+					row.anomaly = fmt.Sprintf("AHEAD(+%d)", committed.at-hwm.at)
+					row.lag = "0"
+				} else if committed.at > end.at {
+					// Committed past the last stable offset
+					// but not the high watermark; there is
+					// nothing stable left to consume.
+					row.lag = "0"
+				} else if start.err == nil && start.at >= 0 && committed.at < start.at {
+					row.anomaly = fmt.Sprintf("EXPIRED(-%d)", start.at-committed.at)
+					row.lag = strconv.FormatInt(end.at-start.at, 10)
+				}
+			}
+			useAnomaly = useAnomaly || row.anomaly != ""
 
-					rows = append(rows, row)
+			if problemsOnly && row.anomaly == "" {
+				return
+			}
+			rows = append(rows, row)
+		}
 
+		for i := range group.Members {
+			member := &group.Members[i]
+			for _, topic := range member.MemberAssignment.Topics {
+				t := topic.Topic
+				if owned[t] == nil {
+					owned[t] = make(map[int32]bool)
+				}
+				for _, p := range topic.Partitions {
+					owned[t][p] = true
+					addRow(t, p, member)
+				}
+			}
+		}
+		for t, partitions := range committedOffsets {
+			for p := range partitions {
+				if !owned[t][p] {
+					addRow(t, p, nil)
 				}
 			}
 		}
 
-		printDescribedGroup(group, rows, useInstanceID, useErr)
+		if problemsOnly && len(rows) == 0 {
+			continue
+		}
+		printed = true
+		printDescribedGroup(group, rows, useInstanceID, useErr, useAnomaly)
 		fmt.Println()
 	}
+	if problemsOnly && !printed {
+		fmt.Println("No committed offsets are out of range.")
+	}
 }
 
-func printDescribedGroup(group describedGroup, rows []describeRow, useInstanceID bool, useErr bool) {
+func printDescribedGroup(group describedGroup, rows []describeRow, useInstanceID, useErr, useAnomaly bool) {
 	tw := out.NewTabWriter()
 	fmt.Fprintf(tw, "GROUP\t%s\n", group.Group)
 	fmt.Fprintf(tw, "COORDINATOR\t%d\n", group.Broker.NodeID)
@@ -356,7 +454,6 @@ func printDescribedGroup(group describedGroup, rows []describeRow, useInstanceID
 		"CURRENT-OFFSET",
 		"LOG-END-OFFSET",
 		"LAG",
-		"MEMBER-ID",
 	}
 	args := func(r *describeRow) []interface{} {
 		return []interface{}{
@@ -365,7 +462,22 @@ func printDescribedGroup(group describedGroup, rows []describeRow, useInstanceID
 			r.currentOffset,
 			r.logEndOffset,
 			r.lag,
-			r.memberID,
+		}
+	}
+
+	if useAnomaly {
+		headers = append(headers, "ANOMALY")
+		orig := args
+		args = func(r *describeRow) []interface{} {
+			return append(orig(r), r.anomaly)
+		}
+	}
+
+	{
+		headers = append(headers, "MEMBER-ID")
+		orig := args
+		args = func(r *describeRow) []interface{} {
+			return append(orig(r), r.memberID)
 		}
 	}
 
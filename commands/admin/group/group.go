@@ -19,7 +19,7 @@ func Command(cl *client.Client) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "group",
 		Aliases: []string{"g"},
-		Short:   "Perform group related actions (list, describe, delete, offset-delete).",
+		Short:   "Perform group related actions (list, describe, delete, offset-delete, stuck).",
 		Args:    cobra.ExactArgs(0),
 	}
 
@@ -28,6 +28,7 @@ func Command(cl *client.Client) *cobra.Command {
 		describeCommand(cl),
 		deleteCommand(cl),
 		offsetDeleteCommand(cl),
+		stuckCommand(cl),
 	)
 
 	return cmd
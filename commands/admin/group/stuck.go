@@ -0,0 +1,352 @@
+package group
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+
+	"github.com/twmb/kcl/client"
+	"github.com/twmb/kcl/out"
+)
+
+func stuckCommand(cl *client.Client) *cobra.Command {
+	var (
+		window  time.Duration
+		windows int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stuck GROUP",
+		Short: "Detect stuck or falling behind group members by sampling offsets over time",
+		Long: `Detect stuck or falling behind group members by sampling offsets over time.
+
+A single describe cannot distinguish a slow consumer from a stuck one. This
+command samples the group's members, committed offsets, and log end offsets
+--windows times, --window apart (taking one more sample than windows), and
+judges every partition assigned to a member:
+
+  STUCK       the committed offset did not advance at all from the first to
+              the last sample while the log end offset did; this is the
+              canonical signature of a stuck consumer
+  BEHIND      lag (log end minus committed offset) grew in every window, and
+              grew by more than the lag in the first sample; see below
+  OK          the committed offset kept up with production
+  NO-COMMIT   the partition had no committed offset in the first or last
+              sample, as is normal for a member that was just assigned
+  REASSIGNED  the partition was not owned by the same member in every sample,
+              so it cannot be judged
+  ERROR       fetching a committed or log end offset failed
+
+Commit and produce rates are computed per partition and per member, in records
+per second over the sampled span. A member's status is its worst partition's
+status.
+
+Consumers commit periodically, so the lag of a healthy consumer that is
+caught up rises and falls between commits. For this reason, BEHIND requires
+lag to grow in every window (not just from the first sample to the last), and
+to grow by more than it started at, i.e., to more than double. A single short
+window can also flag a healthy consumer that paused briefly. Use --windows N
+to sample over a longer span; a consumer that paused in one window but
+committed in another is not stuck, and a consumer whose lag fell in any window
+is not behind.
+
+This exits non-zero if any member is stuck.
+`,
+		Example: `stuck my-group --window 60s
+
+stuck my-group --window 30s --windows 4 -j`,
+		Args: cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			if window <= 0 {
+				out.Die("--window must be positive")
+			}
+			if windows < 1 {
+				out.Die("--windows must be at least 1")
+			}
+			group := args[0]
+
+			samples := make([]stuckSample, 0, windows+1)
+			for i := 0; i <= windows; i++ {
+				if i > 0 {
+					fmt.Fprintf(os.Stderr, "Sampled group %s (%d/%d), waiting %s...\n", group, i, windows+1, window)
+					time.Sleep(time.Until(samples[i-1].at.Add(window)))
+				}
+				samples = append(samples, sampleGroup(cl, group))
+			}
+
+			report := compareSamples(group, window, windows, samples)
+			if cl.AsJSON() {
+				if report.Stuck > 0 {
+					out.ExitErrJSON(report, "%d of %d members of group %s are stuck", report.Stuck, len(report.Members), group)
+				}
+				out.ExitJSON(report)
+			}
+			report.print()
+			if report.Stuck > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().DurationVar(&window, "window", time.Minute, "how long to wait between samples")
+	cmd.Flags().IntVar(&windows, "windows", 1, "number of windows to sample over; more windows are more robust against momentary pauses")
+
+	return cmd
+}
+
+// stuckSample is a point in time view of a group's members and offsets.
+type stuckSample struct {
+	at        time.Time
+	group     describedGroup
+	owners    map[string]map[int32]*describedGroupMember
+	committed map[string]map[int32]offset
+	ends      map[string]map[int32]offset
+}
+
+func sampleGroup(cl *client.Client, group string) stuckSample {
+	s := stuckSample{at: time.Now()}
+
+	described := describeGroups(cl, []string{group})
+	var found bool
+	for _, g := range described {
+		if g.Group == group {
+			s.group, found = g, true
+		}
+	}
+	if !found {
+		out.Die("group %s was not described", group)
+	}
+	if err := kerr.ErrorForCode(s.group.ErrorCode); err != nil {
+		out.Die("unable to describe group %s: %v", group, err)
+	}
+	if len(s.group.Members) == 0 {
+		out.Die("group %s has no members (state %s)", group, s.group.State)
+	}
+
+	s.owners = make(map[string]map[int32]*describedGroupMember)
+	for i := range s.group.Members {
+		member := &s.group.Members[i]
+		for _, topic := range member.MemberAssignment.Topics {
+			if s.owners[topic.Topic] == nil {
+				s.owners[topic.Topic] = make(map[int32]*describedGroupMember)
+			}
+			for _, p := range topic.Partitions {
+				s.owners[topic.Topic][p] = member
+			}
+		}
+	}
+
+	// We fetch committed offsets before listing end offsets so that a
+	// consumer that is caught up is never seen as ahead of the end.
+	s.committed = fetchOffsets(cl, []string{group})[group]
+	s.ends = listOffsets(cl, []describedGroup{s.group}, nil, false, -1)
+	return s
+}
+
+type stuckPartition struct {
+	Topic          string  `json:"topic"`
+	Partition      int32   `json:"partition"`
+	MemberID       string  `json:"member_id"`
+	Status         string  `json:"status"`
+	FirstCommitted int64   `json:"first_committed"`
+	LastCommitted  int64   `json:"last_committed"`
+	FirstLogEnd    int64   `json:"first_log_end"`
+	LastLogEnd     int64   `json:"last_log_end"`
+	CommitRate     float64 `json:"commit_rate"`
+	ProduceRate    float64 `json:"produce_rate"`
+	Lag            int64   `json:"lag"`
+	Error          string  `json:"error,omitempty"`
+}
+
+type stuckMember struct {
+	MemberID    string  `json:"member_id"`
+	InstanceID  *string `json:"instance_id,omitempty"`
+	ClientID    string  `json:"client_id"`
+	Host        string  `json:"host"`
+	Status      string  `json:"status"`
+	Partitions  int     `json:"partitions"`
+	CommitRate  float64 `json:"commit_rate"`
+	ProduceRate float64 `json:"produce_rate"`
+	Lag         int64   `json:"lag"`
+}
+
+type stuckReport struct {
+	Group      string           `json:"group"`
+	Window     string           `json:"window"`
+	Windows    int              `json:"windows"`
+	Span       string           `json:"span"`
+	Stuck      int              `json:"stuck"`
+	Members    []stuckMember    `json:"members"`
+	Partitions []stuckPartition `json:"partitions"`
+}
+
+// statusRanks orders statuses from least to most severe, for picking a
+// member's worst partition.
+var statusRanks = map[string]int{
+	"OK":         0,
+	"NO-COMMIT":  1,
+	"REASSIGNED": 2,
+	"ERROR":      3,
+	"BEHIND":     4,
+	"STUCK":      5,
+}
+
+func compareSamples(group string, window time.Duration, windows int, samples []stuckSample) *stuckReport {
+	first, last := &samples[0], &samples[len(samples)-1]
+	span := last.at.Sub(first.at)
+	report := &stuckReport{
+		Group:      group,
+		Window:     window.String(),
+		Windows:    windows,
+		Span:       span.Round(time.Millisecond).String(),
+		Members:    make([]stuckMember, 0),
+		Partitions: make([]stuckPartition, 0),
+	}
+
+	rate := func(delta int64) float64 { return float64(delta) / span.Seconds() }
+
+	members := make(map[string]*stuckMember)
+	for i := range last.group.Members {
+		m := &last.group.Members[i]
+		members[m.MemberID] = &stuckMember{
+			MemberID:   m.MemberID,
+			InstanceID: m.InstanceID,
+			ClientID:   m.ClientID,
+			Host:       m.ClientHost,
+			Status:     "OK",
+		}
+	}
+
+	for topic, partitions := range last.owners {
+		for partition, owner := range partitions {
+			p := stuckPartition{
+				Topic:     topic,
+				Partition: partition,
+				MemberID:  owner.MemberID,
+			}
+
+			firstCommitted := lookupOffset(first.committed, topic, partition)
+			lastCommitted := lookupOffset(last.committed, topic, partition)
+			firstEnd := lookupOffset(first.ends, topic, partition)
+			lastEnd := lookupOffset(last.ends, topic, partition)
+			p.FirstCommitted, p.LastCommitted = firstCommitted.at, lastCommitted.at
+			p.FirstLogEnd, p.LastLogEnd = firstEnd.at, lastEnd.at
+
+			reassigned := false
+			for _, s := range samples {
+				if o := s.owners[topic][partition]; o == nil || o.MemberID != owner.MemberID {
+					reassigned = true
+				}
+			}
+
+			// We track the lag at every sample so that BEHIND is
+			// not decided by where in the commit interval the
+			// first and last samples happened to land.
+			var err error
+			lags := make([]int64, len(samples))
+			for i := range samples {
+				committed := lookupOffset(samples[i].committed, topic, partition)
+				end := lookupOffset(samples[i].ends, topic, partition)
+				for _, o := range []offset{committed, end} {
+					if err == nil {
+						err = o.err
+					}
+				}
+				lags[i] = end.at
+				if committed.at >= 0 {
+					lags[i] = end.at - committed.at
+				}
+			}
+			growing := true
+			for i := 1; i < len(lags); i++ {
+				if lags[i] <= lags[i-1] {
+					growing = false
+				}
+			}
+			behind := growing && lags[len(lags)-1]-lags[0] > lags[0]
+
+			var committedDelta int64
+			if p.FirstCommitted >= 0 {
+				committedDelta = p.LastCommitted - p.FirstCommitted
+			}
+			endDelta := p.LastLogEnd - p.FirstLogEnd
+			p.CommitRate, p.ProduceRate = rate(committedDelta), rate(endDelta)
+			p.Lag = p.LastLogEnd
+			if p.LastCommitted >= 0 {
+				p.Lag = p.LastLogEnd - p.LastCommitted
+			}
+
+			switch {
+			case err != nil:
+				p.Status, p.Error = "ERROR", err.Error()
+			case reassigned:
+				p.Status = "REASSIGNED"
+			case p.FirstCommitted < 0 || p.LastCommitted < 0:
+				p.Status = "NO-COMMIT"
+			case p.FirstCommitted == p.LastCommitted && endDelta > 0 && p.Lag > 0:
+				p.Status = "STUCK"
+			case behind:
+				p.Status = "BEHIND"
+			default:
+				p.Status = "OK"
+			}
+			report.Partitions = append(report.Partitions, p)
+
+			m := members[owner.MemberID]
+			m.Partitions++
+			m.CommitRate += p.CommitRate
+			m.ProduceRate += p.ProduceRate
+			m.Lag += p.Lag
+			if statusRanks[p.Status] > statusRanks[m.Status] {
+				m.Status = p.Status
+			}
+		}
+	}
+
+	for _, m := range members {
+		if m.Status == "STUCK" {
+			report.Stuck++
+		}
+		report.Members = append(report.Members, *m)
+	}
+	sort.Slice(report.Members, func(i, j int) bool {
+		return report.Members[i].MemberID < report.Members[j].MemberID
+	})
+	sort.Slice(report.Partitions, func(i, j int) bool {
+		l, r := &report.Partitions[i], &report.Partitions[j]
+		return l.Topic < r.Topic || l.Topic == r.Topic && l.Partition < r.Partition
+	})
+	return report
+}
+
+func (r *stuckReport) print() {
+	tw := out.NewTabWriter()
+	fmt.Fprintf(tw, "GROUP\t%s\n", r.Group)
+	fmt.Fprintf(tw, "WINDOWS\t%d x %s (%s total)\n", r.Windows, r.Window, r.Span)
+	fmt.Fprintf(tw, "STUCK\t%d of %d members\n", r.Stuck, len(r.Members))
+	tw.Flush()
+	fmt.Println()
+
+	tw = out.NewTable("MEMBER-ID", "CLIENT-ID", "HOST", "STATUS", "PARTITIONS", "COMMIT-RATE", "PRODUCE-RATE", "LAG")
+	for _, m := range r.Members {
+		tw.Print(m.MemberID, m.ClientID, m.Host, m.Status, m.Partitions,
+			fmt.Sprintf("%.2f/s", m.CommitRate), fmt.Sprintf("%.2f/s", m.ProduceRate), m.Lag)
+	}
+	tw.Flush()
+	fmt.Println()
+
+	tw = out.NewTable("TOPIC", "PARTITION", "MEMBER-ID", "STATUS", "COMMITTED", "LOG-END", "COMMIT-RATE", "PRODUCE-RATE", "LAG", "ERROR")
+	for _, p := range r.Partitions {
+		tw.Print(p.Topic, p.Partition, p.MemberID, p.Status,
+			fmt.Sprintf("%d -> %d", p.FirstCommitted, p.LastCommitted),
+			fmt.Sprintf("%d -> %d", p.FirstLogEnd, p.LastLogEnd),
+			fmt.Sprintf("%.2f/s", p.CommitRate), fmt.Sprintf("%.2f/s", p.ProduceRate),
+			p.Lag, p.Error)
+	}
+	tw.Flush()
+}
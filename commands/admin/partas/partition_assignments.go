@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/twmb/franz-go/pkg/kerr"
@@ -23,8 +24,12 @@ func Command(cl *client.Client) *cobra.Command {
 	return cmd
 }
 
+// alterTimeout is the minimum request timeout for altering assignments, which
+// requires the controller to start reassignments for every partition.
+const alterTimeout = 30 * time.Second
+
 func alterPartitionAssignments(cl *client.Client) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "alter",
 		Short: "Alter partition assignments.",
 		Long: `Alter which brokers partitions are assigned to (Kafka 2.4.0+).
@@ -46,7 +51,7 @@ reassignment for that partition.
 			out.MaybeDie(err, "unable to parse topic partitions replicas: %v", err)
 
 			req := &kmsg.AlterPartitionAssignmentsRequest{
-				TimeoutMillis: cl.TimeoutMillis(),
+				TimeoutMillis: cl.TimeoutMillisAtLeast(alterTimeout, "partition reassignments"),
 			}
 			for topic, partitions := range tprs {
 				if len(partitions) == 0 {
@@ -92,10 +97,12 @@ reassignment for that partition.
 			}
 		},
 	}
+	cl.AddTimeoutFlag(cmd)
+	return cmd
 }
 
 func listPartitionReassignments(cl *client.Client) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:     "list",
 		Aliases: []string{"ls"},
 		Short:   "List partition reassignments.",
@@ -151,4 +158,6 @@ If no topics are specified, this lists all active reassignments.
 			}
 		},
 	}
+	cl.AddTimeoutFlag(cmd)
+	return cmd
 }
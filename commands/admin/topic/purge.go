@@ -0,0 +1,461 @@
+package topic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/twmb/kcl/client"
+)
+
+func topicPurgeCommand(cl *client.Client) *cobra.Command {
+	var (
+		viaDeleteRecords bool
+		waitTimeout      time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "purge TOPIC",
+		Short: "Empty a topic by deleting and recreating it with identical settings",
+		Long: `Empty a topic by deleting and recreating it (Kafka 0.10.1+).
+
+Emptying a topic with delete-records can be slow on huge topics and leaves old
+segments around until log cleanup runs, while deleting and recreating a topic
+by hand loses the topic's configs and races with the deletion propagating.
+
+This command does the delete and recreate safely:
+
+  1) captures the topic's partition count, replication factor, and dynamic
+     (non-default) configs
+  2) deletes the topic
+  3) waits until metadata no longer returns the topic
+  4) recreates the topic with the captured settings
+  5) waits until every partition has a leader
+
+Waiting in steps 3 through 5 is bounded by --wait-timeout.
+
+ACLs reference topics by name and are not removed when a topic is deleted, so
+any ACLs for the topic apply to the recreated topic as well. Custom replica
+assignments are not preserved; the recreated topic uses broker-chosen
+assignments with the same replication factor.
+
+If topic deletion is disabled on the cluster (delete.topic.enable=false), the
+--via-delete-records flag instead deletes all records up to the current end
+offset of every partition, leaving the topic itself in place.
+
+If this command is interrupted or fails midway, it prints what state the topic
+is in and how to recover.
+`,
+		Example: `purge foo
+
+purge foo --via-delete-records`,
+		Args: cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			sigs := make(chan os.Signal, 2)
+			signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+			go func() {
+				<-sigs
+				cancel()
+			}()
+
+			p := &purger{
+				cl:          cl,
+				ctx:         ctx,
+				topic:       args[0],
+				waitTimeout: waitTimeout,
+			}
+			p.capture()
+			if viaDeleteRecords {
+				p.deleteRecords()
+			} else {
+				p.delete()
+				p.create()
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&viaDeleteRecords, "via-delete-records", false, "delete all records up to the end offset of every partition rather than deleting and recreating the topic (Kafka 0.11.0+)")
+	cmd.Flags().DurationVar(&waitTimeout, "wait-timeout", time.Minute, "how long to wait for the deletion to propagate and for the recreated topic to have leaders")
+	cl.AddTimeoutFlag(cmd)
+
+	return cmd
+}
+
+type purgeStage int8
+
+const (
+	purgeCapturing purgeStage = iota
+	purgeDeleting
+	purgeDeleted
+	purgeCreating
+	purgeCreated
+	purgeDeletingRecords
+)
+
+// purger deletes and recreates a topic, tracking what stage the purge is in
+// so that we can tell the user how to recover if something fails.
+type purger struct {
+	cl  *client.Client
+	ctx context.Context
+
+	topic       string
+	waitTimeout time.Duration
+
+	stage purgeStage
+
+	partitions int32
+	replicas   int16
+	configs    []kmsg.CreateTopicsRequestTopicConfig
+}
+
+// die prints a message and the current state of the topic and exits with 1.
+func (p *purger) die(msg string, args ...interface{}) {
+	if errors.Is(p.ctx.Err(), context.Canceled) {
+		msg = "interrupted: " + msg
+	}
+	fmt.Fprintf(os.Stderr, msg+"\n\n", args...)
+	fmt.Fprintln(os.Stderr, p.state())
+	os.Exit(1)
+}
+
+func (p *purger) maybeDie(err error, msg string, args ...interface{}) {
+	if err != nil {
+		p.die(msg, args...)
+	}
+}
+
+// recreateCommand returns the kcl command to recreate the topic with the
+// captured settings.
+func (p *purger) recreateCommand() string {
+	cmd := fmt.Sprintf("kcl admin topic create %s -p %d -r %d", p.topic, p.partitions, p.replicas)
+	for _, c := range p.configs {
+		cmd += " -k " + shellQuote(c.Name+"="+*c.Value)
+	}
+	return cmd
+}
+
+// shellQuote single quotes s for a POSIX shell, so that the recreate command
+// can be pasted as is no matter what characters configs contain.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (p *purger) state() string {
+	switch p.stage {
+	case purgeCapturing:
+		return fmt.Sprintf("STATE: topic %s has not been modified.", p.topic)
+	case purgeDeleting:
+		return fmt.Sprintf(`STATE: deletion of topic %[1]s was requested, but was not confirmed.
+The topic may still exist, may be in the middle of being deleted, or may be gone.
+Check with:
+  kcl metadata -t %[1]s
+If the topic is gone, recreate it with:
+  %[2]s`, p.topic, p.recreateCommand())
+	case purgeDeleted:
+		return fmt.Sprintf(`STATE: topic %s was deleted and has NOT been recreated.
+Recreate it with:
+  %s`, p.topic, p.recreateCommand())
+	case purgeCreating:
+		return fmt.Sprintf(`STATE: topic %[1]s was deleted, and recreating it was requested but not confirmed.
+Check with:
+  kcl metadata -t %[1]s
+If the topic does not exist, recreate it with:
+  %[2]s`, p.topic, p.recreateCommand())
+	case purgeCreated:
+		return fmt.Sprintf(`STATE: topic %[1]s was recreated, but not all partitions were seen with a leader.
+Leaders are usually elected shortly; check with:
+  kcl metadata -t %[1]s -d`, p.topic)
+	default: // purgeDeletingRecords
+		return fmt.Sprintf(`STATE: records in topic %s were requested for deletion, but deletion may be incomplete.
+Some partitions may still contain records; rerun this command with --via-delete-records.`, p.topic)
+	}
+}
+
+// metadata returns the metadata for our topic.
+func (p *purger) metadata() (*kmsg.MetadataResponseTopic, error) {
+	req := kmsg.NewPtrMetadataRequest()
+	reqTopic := kmsg.NewMetadataRequestTopic()
+	reqTopic.Topic = kmsg.StringPtr(p.topic)
+	req.Topics = append(req.Topics, reqTopic)
+
+	resp, err := req.RequestWith(p.ctx, p.cl.Client())
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Topics) != 1 {
+		return nil, fmt.Errorf("metadata returned %d topics when we asked for one", len(resp.Topics))
+	}
+	return &resp.Topics[0], nil
+}
+
+// wait calls fn until it returns true or an error, or until our wait timeout
+// elapses.
+func (p *purger) wait(what string, fn func() (bool, error)) {
+	deadline := time.Now().Add(p.waitTimeout)
+	for {
+		done, err := fn()
+		p.maybeDie(err, "unable to %s: %v", what, err)
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			p.die("timed out after %s waiting to %s", p.waitTimeout, what)
+		}
+		select {
+		case <-p.ctx.Done():
+			p.die("stopped waiting to %s", what)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+func (p *purger) capture() {
+	t, err := p.metadata()
+	p.maybeDie(err, "unable to request metadata: %v", err)
+	if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+		p.die("unable to describe topic %s: %v", p.topic, err)
+	}
+	if len(t.Partitions) == 0 {
+		p.die("topic %s has no partitions", p.topic)
+	}
+	// While a partition is being reassigned, its metadata replicas
+	// include the replicas being added, so we cannot tell the topic's
+	// actual replication factor.
+	if p.reassigning(t) {
+		p.die("topic %s has a partition reassignment in progress; wait for it to complete (see kcl admin partas list) before purging", p.topic)
+	}
+	p.partitions = int32(len(t.Partitions))
+	p.replicas = int16(len(t.Partitions[0].Replicas))
+	for _, partition := range t.Partitions {
+		if len(partition.Replicas) != int(p.replicas) {
+			p.die("topic %s has partitions with differing replication factors (possibly from a partition reassignment in progress); unable to recreate it faithfully", p.topic)
+		}
+	}
+
+	req := kmsg.NewPtrDescribeConfigsRequest()
+	reqResource := kmsg.NewDescribeConfigsRequestResource()
+	reqResource.ResourceType = kmsg.ConfigResourceTypeTopic
+	reqResource.ResourceName = p.topic
+	req.Resources = append(req.Resources, reqResource)
+
+	resp, err := req.RequestWith(p.ctx, p.cl.Client())
+	p.maybeDie(err, "unable to describe configs: %v", err)
+	if len(resp.Resources) != 1 {
+		p.die("describe configs returned %d resources when we asked for one", len(resp.Resources))
+	}
+	resource := resp.Resources[0]
+	if err := kerr.ErrorForCode(resource.ErrorCode); err != nil {
+		p.die("unable to describe configs for topic %s: %v", p.topic, err)
+	}
+	for _, c := range resource.Configs {
+		dynamic := c.Source == kmsg.ConfigSourceDynamicTopicConfig
+		if resp.Version == 0 {
+			dynamic = !c.IsDefault && !c.ReadOnly
+		}
+		if !dynamic {
+			continue
+		}
+		if c.Value == nil {
+			p.die("topic %s config %s has no value (is it sensitive?); unable to recreate it faithfully", p.topic, c.Name)
+		}
+		p.configs = append(p.configs, kmsg.CreateTopicsRequestTopicConfig{
+			Name:  c.Name,
+			Value: c.Value,
+		})
+	}
+	sort.Slice(p.configs, func(i, j int) bool { return p.configs[i].Name < p.configs[j].Name })
+
+	var names []string
+	for _, c := range p.configs {
+		names = append(names, c.Name)
+	}
+	fmt.Printf("Captured topic %s: %d partitions, replication factor %d, dynamic configs [%s].\n",
+		p.topic, p.partitions, p.replicas, strings.Join(names, ", "))
+}
+
+// reassigning returns whether any partition of the topic is being reassigned.
+// Brokers before Kafka 2.4.0 cannot list reassignments, in which case, or on
+// any other request error, this returns false and we rely on the replication
+// factor check in capture.
+func (p *purger) reassigning(t *kmsg.MetadataResponseTopic) bool {
+	req := kmsg.NewPtrListPartitionReassignmentsRequest()
+	req.TimeoutMillis = p.cl.TimeoutMillis()
+	reqTopic := kmsg.NewListPartitionReassignmentsRequestTopic()
+	reqTopic.Topic = p.topic
+	for _, partition := range t.Partitions {
+		reqTopic.Partitions = append(reqTopic.Partitions, partition.Partition)
+	}
+	req.Topics = append(req.Topics, reqTopic)
+
+	resp, err := req.RequestWith(p.ctx, p.cl.Client())
+	if err != nil || resp.ErrorCode != 0 {
+		return false
+	}
+	for _, topic := range resp.Topics {
+		if topic.Topic == p.topic && len(topic.Partitions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *purger) delete() {
+	p.stage = purgeDeleting
+
+	req := kmsg.NewPtrDeleteTopicsRequest()
+	req.TimeoutMillis = p.cl.TimeoutMillis()
+	req.TopicNames = []string{p.topic}
+	reqTopic := kmsg.NewDeleteTopicsRequestTopic()
+	reqTopic.Topic = kmsg.StringPtr(p.topic)
+	req.Topics = append(req.Topics, reqTopic)
+
+	resp, err := req.RequestWith(p.ctx, p.cl.Client())
+	p.maybeDie(err, "unable to delete topic: %v", err)
+	if len(resp.Topics) != 1 {
+		p.die("delete topics returned %d topics when we asked for one", len(resp.Topics))
+	}
+	switch err := kerr.ErrorForCode(resp.Topics[0].ErrorCode); err {
+	case nil, kerr.RequestTimedOut:
+		// A timeout means the controller is still deleting the
+		// topic; we wait for it below.
+	case kerr.TopicDeletionDisabled:
+		p.stage = purgeCapturing
+		p.die("unable to delete topic %s: %v; use --via-delete-records to purge the topic without deleting it", p.topic, err)
+	default:
+		p.die("unable to delete topic %s: %v", p.topic, err)
+	}
+
+	p.wait("wait for the topic deletion to propagate", func() (bool, error) {
+		t, err := p.metadata()
+		if err != nil {
+			return false, err
+		}
+		return t.ErrorCode == kerr.UnknownTopicOrPartition.Code, nil
+	})
+	p.stage = purgeDeleted
+	fmt.Printf("Deleted topic %s.\n", p.topic)
+}
+
+func (p *purger) create() {
+	p.stage = purgeCreating
+
+	req := kmsg.NewPtrCreateTopicsRequest()
+	req.TimeoutMillis = createTimeoutMillis(p.cl, int(p.partitions))
+	reqTopic := kmsg.NewCreateTopicsRequestTopic()
+	reqTopic.Topic = p.topic
+	reqTopic.NumPartitions = p.partitions
+	reqTopic.ReplicationFactor = p.replicas
+	reqTopic.Configs = p.configs
+	req.Topics = append(req.Topics, reqTopic)
+
+	// Even once metadata no longer returns the topic, some brokers may
+	// still be finishing the deletion; we retry TopicAlreadyExists until
+	// our wait timeout.
+	p.wait("recreate the topic", func() (bool, error) {
+		resp, err := req.RequestWith(p.ctx, p.cl.Client())
+		if err != nil {
+			return false, err
+		}
+		if len(resp.Topics) != 1 {
+			return false, fmt.Errorf("create topics returned %d topics when we asked for one", len(resp.Topics))
+		}
+		switch err := kerr.ErrorForCode(resp.Topics[0].ErrorCode); err {
+		case nil:
+			return true, nil
+		case kerr.TopicAlreadyExists:
+			return false, nil
+		default:
+			return false, err
+		}
+	})
+	p.stage = purgeCreated
+	fmt.Printf("Recreated topic %s.\n", p.topic)
+
+	p.wait("wait for all partitions to have leaders", func() (bool, error) {
+		t, err := p.metadata()
+		if err != nil {
+			return false, err
+		}
+		if t.ErrorCode != 0 || len(t.Partitions) != int(p.partitions) {
+			return false, nil
+		}
+		for _, partition := range t.Partitions {
+			if partition.ErrorCode != 0 || partition.Leader < 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	fmt.Printf("All %d partitions of topic %s have leaders.\n", p.partitions, p.topic)
+}
+
+func (p *purger) deleteRecords() {
+	listReq := kmsg.NewPtrListOffsetsRequest()
+	listReq.ReplicaID = -1
+	listTopic := kmsg.NewListOffsetsRequestTopic()
+	listTopic.Topic = p.topic
+	for i := int32(0); i < p.partitions; i++ {
+		listPartition := kmsg.NewListOffsetsRequestTopicPartition()
+		listPartition.Partition = i
+		listPartition.Timestamp = -1 // latest
+		listTopic.Partitions = append(listTopic.Partitions, listPartition)
+	}
+	listReq.Topics = append(listReq.Topics, listTopic)
+
+	delReq := kmsg.NewPtrDeleteRecordsRequest()
+	delReq.TimeoutMillis = p.cl.TimeoutMillis()
+	delTopic := kmsg.NewDeleteRecordsRequestTopic()
+	delTopic.Topic = p.topic
+
+	for _, shard := range p.cl.Client().RequestSharded(p.ctx, listReq) {
+		if shard.Err != nil {
+			p.die("unable to list end offsets from broker %d: %v", shard.Meta.NodeID, shard.Err)
+		}
+		for _, t := range shard.Resp.(*kmsg.ListOffsetsResponse).Topics {
+			for _, partition := range t.Partitions {
+				if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
+					p.die("unable to list end offset for partition %d: %v", partition.Partition, err)
+				}
+				delPartition := kmsg.NewDeleteRecordsRequestTopicPartition()
+				delPartition.Partition = partition.Partition
+				delPartition.Offset = partition.Offset
+				delTopic.Partitions = append(delTopic.Partitions, delPartition)
+			}
+		}
+	}
+	delReq.Topics = append(delReq.Topics, delTopic)
+
+	p.stage = purgeDeletingRecords
+	var failed bool
+	for _, shard := range p.cl.Client().RequestSharded(p.ctx, delReq) {
+		if shard.Err != nil {
+			fmt.Fprintf(os.Stderr, "unable to delete records on broker %d: %v\n", shard.Meta.NodeID, shard.Err)
+			failed = true
+			continue
+		}
+		for _, t := range shard.Resp.(*kmsg.DeleteRecordsResponse).Topics {
+			for _, partition := range t.Partitions {
+				if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
+					fmt.Fprintf(os.Stderr, "unable to delete records in partition %d: %v\n", partition.Partition, err)
+					failed = true
+				}
+			}
+		}
+	}
+	if failed {
+		p.die("unable to delete all records")
+	}
+	fmt.Printf("Deleted all records in the %d partitions of topic %s.\n", p.partitions, p.topic)
+}
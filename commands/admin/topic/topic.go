@@ -9,6 +9,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -21,17 +22,34 @@ import (
 	"github.com/twmb/kcl/out"
 )
 
+// Creating many partitions can take the controller a while, so we ensure that
+// requests creating at least manyPartitions partitions use a request timeout
+// of at least manyPartitionsTimeout.
+const (
+	manyPartitions        = 100
+	manyPartitionsTimeout = 30 * time.Second
+)
+
+// createTimeoutMillis returns the timeout to use when creating partitions.
+func createTimeoutMillis(cl *client.Client, partitions int) int32 {
+	if partitions < manyPartitions {
+		return cl.TimeoutMillis()
+	}
+	return cl.TimeoutMillisAtLeast(manyPartitionsTimeout, fmt.Sprintf("creating %d partitions", partitions))
+}
+
 func Command(cl *client.Client) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "topic",
 		Aliases: []string{"t"},
-		Short:   "Perform topic relation actions (create, list, delete, add-partitions).",
+		Short:   "Perform topic relation actions (create, list, delete, add-partitions, purge).",
 	}
 
 	cmd.AddCommand(topicCreateCommand(cl))
 	cmd.AddCommand(topicListCommand(cl))
 	cmd.AddCommand(topicDeleteCommand(cl))
 	cmd.AddCommand(topicAddPartitionsCommand(cl))
+	cmd.AddCommand(topicPurgeCommand(cl))
 	return cmd
 }
 
@@ -54,28 +72,11 @@ replication factor, and key/value configs.
 `,
 		Args: cobra.MinimumNArgs(1),
 		Run: func(_ *cobra.Command, args []string) {
-			kvs, err := kv.Parse(configKVs)
-			out.MaybeDie(err, "unable to parse KVs: %v", err)
-			req := kmsg.CreateTopicsRequest{TimeoutMillis: cl.TimeoutMillis()}
+			req, err := NewCreateRequest(cl, args, numPartitions, replicationFactor, configKVs)
+			out.MaybeDie(err, "%v", err)
 			req.ValidateOnly = validateOnly
-			var configs []kmsg.CreateTopicsRequestTopicConfig
-			for _, kv := range kvs {
-				configs = append(configs, kmsg.CreateTopicsRequestTopicConfig{
-					Name:  kv.K,
-					Value: kmsg.StringPtr(kv.V),
-				})
-			}
 
-			for _, topic := range args {
-				req.Topics = append(req.Topics, kmsg.CreateTopicsRequestTopic{
-					Topic:             topic,
-					ReplicationFactor: replicationFactor,
-					NumPartitions:     numPartitions,
-					Configs:           configs,
-				})
-			}
-
-			kresp, err := cl.Client().Request(context.Background(), &req)
+			kresp, err := cl.Client().Request(context.Background(), req)
 			out.MaybeDie(err, "unable to create topic %q: %v", args[0], err)
 			if cl.AsJSON() {
 				out.ExitJSON(kresp)
@@ -107,10 +108,38 @@ replication factor, and key/value configs.
 	cmd.Flags().Int32VarP(&numPartitions, "num-partitions", "p", 20, "number of partitions to create")
 	cmd.Flags().Int16VarP(&replicationFactor, "replication-factor", "r", 1, "number of replicas to have of each partition")
 	cmd.Flags().StringArrayVarP(&configKVs, "kv", "k", nil, "list of key=value config parameters (repeatable, e.g. -k cleanup.policy=compact -k preallocate=true)")
+	cl.AddTimeoutFlag(cmd)
 
 	return cmd
 }
 
+// NewCreateRequest returns the request topic create issues to create topics
+// with the given partitions, replication factor, and key=value configs.
+func NewCreateRequest(cl *client.Client, topics []string, numPartitions int32, replicationFactor int16, configKVs []string) (*kmsg.CreateTopicsRequest, error) {
+	kvs, err := kv.Parse(configKVs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse KVs: %v", err)
+	}
+	req := &kmsg.CreateTopicsRequest{TimeoutMillis: createTimeoutMillis(cl, int(numPartitions)*len(topics))}
+	var configs []kmsg.CreateTopicsRequestTopicConfig
+	for _, kv := range kvs {
+		configs = append(configs, kmsg.CreateTopicsRequestTopicConfig{
+			Name:  kv.K,
+			Value: kmsg.StringPtr(kv.V),
+		})
+	}
+
+	for _, topic := range topics {
+		req.Topics = append(req.Topics, kmsg.CreateTopicsRequestTopic{
+			Topic:             topic,
+			ReplicationFactor: replicationFactor,
+			NumPartitions:     numPartitions,
+			Configs:           configs,
+		})
+	}
+	return req, nil
+}
+
 func topicListCommand(cl *client.Client) *cobra.Command {
 	var detailed bool
 
@@ -179,6 +208,7 @@ func topicDeleteCommand(cl *client.Client) *cobra.Command {
 		},
 	}
 	cmd.Flags().BoolVar(&ids, "ids", false, "whether the input topics should be parsed as topic IDs")
+	cl.AddTimeoutFlag(cmd)
 	return cmd
 }
 
@@ -238,7 +268,7 @@ add-partitions -t bar -t baz 1, 2, 3`,
 			metaResp := kmetaResp.(*kmsg.MetadataResponse)
 
 			createReq := kmsg.CreatePartitionsRequest{
-				TimeoutMillis: cl.TimeoutMillis(),
+				TimeoutMillis: createTimeoutMillis(cl, len(assignments)*len(metaResp.Topics)),
 			}
 			for _, topic := range metaResp.Topics {
 				if topic.Topic == nil {
@@ -285,6 +315,7 @@ add-partitions -t bar -t baz 1, 2, 3`,
 	}
 
 	cmd.Flags().StringArrayVarP(&topics, "topic", "t", nil, "topic to add partitions to; repeatable")
+	cl.AddTimeoutFlag(cmd)
 
 	return cmd
 }
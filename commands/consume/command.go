@@ -24,6 +24,7 @@ func (c *consumption) command() *cobra.Command {
 	cmd.Flags().IntVarP(&c.num, "num", "n", 0, "quit after consuming this number of records; 0 is unbounded")
 	cmd.Flags().IntVar(&c.numPerPartition, "num-per-partition", 0, "stop printing individual partitions after this many records; 0 is unbounded")
 	cmd.Flags().StringVarP(&c.format, "format", "f", `%v\n`, "output format")
+	cmd.Flags().StringVar(&c.formatFile, "format-file", "", "if non-empty, a file to read the output format from (see FORMAT FILES below)")
 	cmd.Flags().BoolVarP(&c.regex, "regex", "r", false, "parse topics as regex; consume any topic that matches any expression")
 	cmd.Flags().StringVarP(&c.escapeChar, "escape-char", "c", "%", "character to use for beginning a record field escape (accepts any utf8)")
 	cmd.Flags().Int32Var(&c.fetchMaxBytes, "fetch-max-bytes", 1<<20, "maximum amount of bytes per fetch request per broker")
@@ -32,7 +33,12 @@ func (c *consumption) command() *cobra.Command {
 	cmd.Flags().BoolVar(&c.readUncommitted, "read-uncommitted", false, "opt in to reading uncommitted offsets")
 	cmd.Flags().StringVar(&c.protoFile, "proto-file", "", "an optional proto source file or protoset file to decode protobuf messages, requires --proto-message")
 	cmd.Flags().StringVar(&c.protoMessage, "proto-message", "", "the proto.message structure in --proto-file to use for decoding, requires --proto-file")
+	cmd.Flags().StringVar(&c.orderedBy, "ordered-by", "", "if non-empty, buffer records until a bounded consume completes and print them sorted (partition, offset, timestamp)")
+	cmd.Flags().IntVar(&c.maxBufferRecords, "max-buffer-records", 1000000, "maximum number of records to buffer when using --ordered-by before quitting with an error")
 	cmd.MarkFlagsRequiredTogether("proto-file", "proto-message")
+	cmd.MarkFlagsMutuallyExclusive("format", "format-file")
+
+	cmd.AddCommand(traceKeyCommand(c.cl))
 	return cmd
 }
 
@@ -158,4 +164,42 @@ Combined with producing, these two commands allow you to easily mirror a topic.
 
 If you do not like %, you can switch the escape character with a flag.
 Unfortunately, with exact sizing, the format string is unavoidably noisy.
+
+
+FORMAT FILES
+
+Complex formats can be written to a file and loaded with --format-file rather
+than passed with -f. In a format file:
+  - lines beginning with # are comments and are stripped entirely
+  - a line ending in a backslash is joined with the next line (a line ending
+    in an escaped backslash, \\, is not)
+  - all other newlines are literally part of the format
+  - a single newline ending the file is dropped
+
+For example, the mirroring format above could be written as:
+  # key and value, each prefixed with a four byte big endian length
+  %K{b4}%k%V{b4}%v\
+  # headers, with the same sizing
+  %H{b4}%h{%K{b4}%k%V{b4}%v}
+
+Errors in a format file are reported with the file and line they occur on.
+
+
+ORDERED OUTPUT
+
+When consuming many partitions, records are printed as they are fetched, which
+interleaves partitions arbitrarily. For consumes that have a defined end (--num
+or an :end offset), the --ordered-by flag buffers records until the consume
+completes and then prints them sorted:
+  partition   by topic and partition, then by offset within each partition
+  offset      by offset, then by topic and partition
+  timestamp   globally by timestamp, then by topic, partition, and offset
+
+Unbounded consumes cannot be ordered and are rejected.
+
+All consumed records are held in memory until the consume completes. To avoid
+accidentally buffering an entire large topic, at most --max-buffer-records
+records are buffered; if more are consumed before the bound completes, kcl
+quits with an error. Interrupting an ordered consume prints what has been
+buffered so far, in order.
 `
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -36,6 +37,7 @@ type consumption struct {
 	num             int
 	numPerPartition int
 	format          string
+	formatFile      string
 	escapeChar      string
 	rack            string
 
@@ -52,6 +54,9 @@ type consumption struct {
 
 	protoFile    string
 	protoMessage string
+
+	orderedBy        string
+	maxBufferRecords int
 }
 
 // Command returns a consume command.
@@ -78,6 +83,18 @@ func (c *consumption) run(topics []string) {
 	}
 
 	offset := c.parseOffset()
+	switch c.orderedBy {
+	case "", "partition", "offset", "timestamp":
+	default:
+		out.Die("unrecognized --ordered-by %q (partition, offset, timestamp)", c.orderedBy)
+	}
+	if c.orderedBy != "" && c.num == 0 && c.untilOffset == -1 {
+		out.Die("--ordered-by requires a bounded consume (--num or an :end offset)")
+	}
+	if c.orderedBy != "" && c.maxBufferRecords <= 0 {
+		out.Die("invalid non-positive --max-buffer-records %d", c.maxBufferRecords)
+	}
+
 	c.cl.AddOpt(kgo.ConsumeResetOffset(offset))
 	if len(c.partitions) == 0 {
 		c.cl.AddOpt(kgo.ConsumeTopics(topics...))
@@ -150,6 +167,8 @@ func (c *consumption) run(topics []string) {
 		start:           c.start,
 		end:             c.end,
 		group:           c.group,
+		orderedBy:       c.orderedBy,
+		maxBuffered:     c.maxBufferRecords,
 		done:            make(chan struct{}),
 		ctx:             ctx,
 		cancel:          cancel,
@@ -233,7 +252,15 @@ func (c *consumption) run(topics []string) {
 	} else if isTransactionState {
 		co.buildTransactionStateFormatFn()
 	} else {
+		var ff *format.File
+		if c.formatFile != "" {
+			var err error
+			ff, err = format.ReadFile(c.formatFile)
+			out.MaybeDie(err, "unable to read format file: %v", err)
+			c.format = ff.Format
+		}
 		fn, err := format.ParseWriteFormat(c.format, escape)
+		err = ff.Err(err)
 		out.MaybeDie(err, "%v", err)
 		var out []byte
 		co.format = func(r *kgo.Record, p *kgo.FetchPartition) {
@@ -251,6 +278,7 @@ func (c *consumption) run(topics []string) {
 		atomic.StoreUint32(&co.quit, 1)
 		co.cancel()
 		<-co.done
+		co.drain() // anything buffered was consumed, so we print it
 		cl.Close() // leaves group
 	}()
 	select {
@@ -341,6 +369,83 @@ type consumeOutput struct {
 	done   chan struct{}
 
 	format func(*kgo.Record, *kgo.FetchPartition)
+
+	// If orderedBy is non-empty, records are buffered rather than
+	// formatted immediately and are drained in order once the consume
+	// bound is hit.
+	orderedBy   string
+	maxBuffered int
+	buffered    []bufferedRecord
+}
+
+type bufferedRecord struct {
+	r *kgo.Record
+	p kgo.FetchPartition
+}
+
+// output formats a record, or buffers it if we are ordering output.
+func (co *consumeOutput) output(r *kgo.Record, p *kgo.FetchPartition) {
+	if co.orderedBy == "" {
+		co.format(r, p)
+		return
+	}
+	if len(co.buffered) == co.maxBuffered {
+		out.Die("consumed more than --max-buffer-records (%d) records before the consume bound completed; quitting", co.maxBuffered)
+	}
+	co.buffered = append(co.buffered, bufferedRecord{r, *p})
+}
+
+// drain sorts and formats all buffered records.
+func (co *consumeOutput) drain() {
+	byPartition := func(l, r *kgo.Record) bool {
+		if l.Topic != r.Topic {
+			return l.Topic < r.Topic
+		}
+		return l.Partition < r.Partition
+	}
+	var less func(l, r *kgo.Record) bool
+	switch co.orderedBy {
+	case "":
+		return
+	case "partition":
+		less = func(l, r *kgo.Record) bool {
+			if l.Topic != r.Topic || l.Partition != r.Partition {
+				return byPartition(l, r)
+			}
+			return l.Offset < r.Offset
+		}
+	case "offset":
+		less = func(l, r *kgo.Record) bool {
+			if l.Offset != r.Offset {
+				return l.Offset < r.Offset
+			}
+			return byPartition(l, r)
+		}
+	case "timestamp":
+		less = func(l, r *kgo.Record) bool {
+			if !l.Timestamp.Equal(r.Timestamp) {
+				return l.Timestamp.Before(r.Timestamp)
+			}
+			if l.Topic != r.Topic || l.Partition != r.Partition {
+				return byPartition(l, r)
+			}
+			return l.Offset < r.Offset
+		}
+	}
+	sort.SliceStable(co.buffered, func(i, j int) bool {
+		return less(co.buffered[i].r, co.buffered[j].r)
+	})
+	for i := range co.buffered {
+		b := &co.buffered[i]
+		co.format(b.r, &b.p)
+	}
+	co.buffered = nil
+}
+
+// exit drains any buffered records and exits successfully.
+func (co *consumeOutput) exit() {
+	co.drain()
+	os.Exit(0)
 }
 
 func (co *consumeOutput) consume() {
@@ -362,7 +467,7 @@ func (co *consumeOutput) consume() {
 
 	for atomic.LoadUint32(&co.quit) == 0 {
 		if len(co.untilOffsets) != 0 && len(offsetsRemaining) == 0 {
-			os.Exit(0)
+			co.exit()
 		}
 
 		fetches := co.cl.PollFetches(co.ctx)
@@ -420,10 +525,10 @@ func (co *consumeOutput) consume() {
 					if co.pbd != nil {
 						r.Value, _ = co.pbd.jsonString(r.Value)
 					}
-					co.format(r, &p.FetchPartition)
+					co.output(r, &p.FetchPartition)
 
 					if co.num == co.max {
-						os.Exit(0)
+						co.exit()
 					}
 				}
 			})
@@ -0,0 +1,256 @@
+package consume
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/twmb/kcl/client"
+	"github.com/twmb/kcl/format"
+	"github.com/twmb/kcl/out"
+)
+
+func traceKeyCommand(cl *client.Client) *cobra.Command {
+	var (
+		key              string
+		keyHex           string
+		allPartitions    bool
+		since            string
+		outFormat        string
+		escapeChar       string
+		progressInterval time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "trace-key TOPIC",
+		Short: "Print every record for a single key in a topic",
+		Long: `Print every record for a single key in a topic.
+
+Finding every record for a key by consuming a whole topic and filtering the
+output is slow on large topics. This command instead determines which
+partition the key is produced to under the default partitioner (murmur2
+hashing, the same as the Java client) and scans only that partition, printing
+records whose key exactly matches.
+
+If the topic is produced to with a custom partitioner, the key may live in any
+partition; use --all-partitions to scan every partition.
+
+By default, partitions are scanned from the start. The --since flag starts
+scanning from the first offset at or after a time, which can be a duration
+ago (e.g. 1h), an RFC3339 timestamp, or a unix millisecond timestamp. Scanning
+stops at the end offsets listed when the command starts.
+
+Matching records are printed with the -f format, which accepts the same
+options as the consume command. While scanning, progress is periodically
+printed to stderr, and once scanning completes, the number of matching records
+is printed to stderr.
+`,
+		Example: `trace-key foo --key user-1234
+
+trace-key foo --key-hex 0a0b0c --all-partitions --since 2h`,
+		Args: cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			topic := args[0]
+
+			if len(escapeChar) == 0 {
+				out.Die("invalid empty escape character")
+			}
+			escape, size := utf8.DecodeRuneInString(escapeChar)
+			if size != len(escapeChar) {
+				out.Die("invalid multi character escape character")
+			}
+			fn, err := format.ParseWriteFormat(outFormat, escape)
+			out.MaybeDie(err, "%v", err)
+
+			var k []byte
+			switch {
+			case key != "" && keyHex != "":
+				out.Die("only one of --key and --key-hex can be specified")
+			case key != "":
+				k = []byte(key)
+			case keyHex != "":
+				k, err = hex.DecodeString(keyHex)
+				out.MaybeDie(err, "unable to decode --key-hex: %v", err)
+			default:
+				out.Die("missing --key or --key-hex")
+			}
+
+			var sinceMilli int64 = -1
+			if since != "" {
+				sinceMilli, err = parseSince(since)
+				out.MaybeDie(err, "unable to parse --since: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			sigs := make(chan os.Signal, 2)
+			signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+			go func() {
+				<-sigs
+				cancel()
+			}()
+
+			// We keep control records so that a partition ending
+			// in a transaction marker still reaches its end offset.
+			cl.AddOpt(kgo.KeepControlRecords())
+			adm := kadm.NewClient(cl.Client())
+
+			ends, err := adm.ListEndOffsets(ctx, topic)
+			out.MaybeDie(err, "unable to list end offsets: %v", err)
+			var starts kadm.ListedOffsets
+			if sinceMilli >= 0 {
+				starts, err = adm.ListOffsetsAfterMilli(ctx, sinceMilli, topic)
+			} else {
+				starts, err = adm.ListStartOffsets(ctx, topic)
+			}
+			out.MaybeDie(err, "unable to list start offsets: %v", err)
+
+			if o, exists := ends.Lookup(topic, -1); exists {
+				out.Die("unable to list offsets for topic %s: %v", topic, o.Err)
+			}
+			numPartitions := len(ends[topic])
+			if numPartitions == 0 {
+				out.Die("topic %s has no partitions", topic)
+			}
+
+			var partitions []int32
+			if allPartitions {
+				for p := range ends[topic] {
+					partitions = append(partitions, p)
+				}
+				sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+			} else {
+				p := kgo.StickyKeyPartitioner(nil).ForTopic(topic).Partition(&kgo.Record{Key: k}, numPartitions)
+				partitions = append(partitions, int32(p))
+			}
+
+			var (
+				offsets = make(map[int32]kgo.Offset)
+				bounds  = make(map[int32]int64) // partition => end offset
+				total   int64
+			)
+			for _, p := range partitions {
+				start, _ := starts.Lookup(topic, p)
+				end, _ := ends.Lookup(topic, p)
+				if start.Err != nil {
+					out.Die("unable to list start offset for partition %d: %v", p, start.Err)
+				}
+				if end.Err != nil {
+					out.Die("unable to list end offset for partition %d: %v", p, end.Err)
+				}
+				if start.Offset < 0 || start.Offset >= end.Offset {
+					continue
+				}
+				offsets[p] = kgo.NewOffset().At(start.Offset)
+				bounds[p] = end.Offset
+				total += end.Offset - start.Offset
+			}
+
+			fmt.Fprintf(os.Stderr, "Scanning %d offsets in partitions %v of topic %s...\n", total, partitions, topic)
+			if len(offsets) == 0 {
+				fmt.Fprintln(os.Stderr, "Matched 0 records.")
+				return
+			}
+
+			consumer := cl.RemakeWithOpts(kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{topic: offsets}))
+			defer consumer.Close()
+
+			var (
+				buf          []byte
+				matched      int64
+				scanned      int64
+				begin        = time.Now()
+				lastProgress = begin
+			)
+			for len(bounds) > 0 {
+				fetches := consumer.PollFetches(ctx)
+				if ctx.Err() != nil {
+					out.Die("Interrupted after scanning %d of %d offsets and matching %d records.", scanned, total, matched)
+				}
+				fetches.EachError(func(t string, p int32, err error) {
+					out.Die("unable to fetch partition %d: %v", p, err)
+				})
+				fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+					end, ok := bounds[p.Partition]
+					if !ok {
+						return
+					}
+					at := offsets[p.Partition].EpochOffset().Offset
+					for _, r := range p.Records {
+						// Offsets just before the end may not exist
+						// (e.g. compacted away), so the first record
+						// at or past the end completes the partition.
+						if r.Offset >= end {
+							scanned += end - at
+							at = end
+							break
+						}
+						scanned += r.Offset + 1 - at
+						at = r.Offset + 1
+						if r.Attrs.IsControl() || !bytes.Equal(r.Key, k) {
+							continue
+						}
+						matched++
+						buf = fn(buf[:0], r, &p.FetchPartition)
+						os.Stdout.Write(buf)
+					}
+					offsets[p.Partition] = kgo.NewOffset().At(at)
+					if at >= end {
+						delete(bounds, p.Partition)
+						consumer.PauseFetchPartitions(map[string][]int32{topic: {p.Partition}})
+					}
+				})
+
+				if progressInterval > 0 && time.Since(lastProgress) >= progressInterval {
+					lastProgress = time.Now()
+					var eta time.Duration
+					if elapsed := time.Since(begin).Seconds(); scanned > 0 && elapsed > 0 {
+						rate := float64(scanned) / elapsed
+						eta = time.Duration(float64(total-scanned) / rate * float64(time.Second)).Round(time.Second)
+					}
+					fmt.Fprintf(os.Stderr, "Scanned %d/%d offsets (%.1f%%), matched %d, ETA %s\n",
+						scanned, total, 100*float64(scanned)/float64(total), matched, eta)
+				}
+			}
+
+			fmt.Fprintf(os.Stderr, "Matched %d records in %d scanned offsets.\n", matched, total)
+		},
+	}
+
+	cmd.Flags().StringVar(&key, "key", "", "key to search for")
+	cmd.Flags().StringVar(&keyHex, "key-hex", "", "hex encoded key to search for")
+	cmd.Flags().BoolVar(&allPartitions, "all-partitions", false, "scan all partitions rather than only the partition the default partitioner maps the key to")
+	cmd.Flags().StringVar(&since, "since", "", "if non-empty, scan from the first offset at or after this time (duration ago, RFC3339, or unix milliseconds) rather than the start")
+	cmd.Flags().StringVarP(&outFormat, "format", "f", `partition %p offset %o at %d{go#2006-01-02T15:04:05.000Z07:00#}: %v\n`, "output format for matching records")
+	cmd.Flags().StringVarP(&escapeChar, "escape-char", "c", "%", "character to use for beginning a record field escape (accepts any utf8)")
+	cmd.Flags().DurationVar(&progressInterval, "progress-interval", 5*time.Second, "how often to print scan progress to stderr; 0 disables progress")
+
+	return cmd
+}
+
+// parseSince parses a duration ago, an RFC3339 timestamp, or unix
+// milliseconds into unix milliseconds.
+func parseSince(since string) (int64, error) {
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d).UnixMilli(), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, since); err == nil {
+		return t.UnixMilli(), nil
+	}
+	if ms, err := strconv.ParseInt(since, 10, 64); err == nil && ms >= 0 {
+		return ms, nil
+	}
+	return 0, fmt.Errorf("%q is not a duration, RFC3339 timestamp, or unix millisecond timestamp", since)
+}
@@ -34,7 +34,7 @@ func apiVersionsRequest() *kmsg.ApiVersionsRequest {
 func Command(cl *client.Client) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "misc",
-		Short: "Miscellaneous utilities (version probing, error code/text, offset listing)",
+		Short: "Miscellaneous utilities (version probing, error code/text, offset listing, self-test)",
 	}
 
 	cmd.AddCommand(errcodeCommand())
@@ -45,6 +45,7 @@ func Command(cl *client.Client) *cobra.Command {
 	cmd.AddCommand(rawCommand(cl))
 	cmd.AddCommand(listOffsetsCommand(cl))
 	cmd.AddCommand(offsetForLeaderEpochCommand(cl))
+	cmd.AddCommand(selfTestCommand(cl))
 
 	return cmd
 }
@@ -0,0 +1,583 @@
+package misc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/twmb/kcl/client"
+	"github.com/twmb/kcl/commands/admin/topic"
+	"github.com/twmb/kcl/format"
+	"github.com/twmb/kcl/out"
+)
+
+func selfTestCommand(cl *client.Client) *cobra.Command {
+	t := &selfTest{cl: cl}
+	var prefix string
+
+	cmd := &cobra.Command{
+		Use:   "self-test",
+		Short: "Validate a cluster end to end by creating, producing to, consuming from, and deleting a topic",
+		Long: `Validate a cluster end to end (Kafka 0.11.0+).
+
+This command runs a scripted sequence of steps against the cluster, printing
+PASS or FAIL for each step as it completes:
+
+  connect         list brokers, validating connectivity and authentication
+  create topic    create a uniquely named topic and wait for partition leaders
+  produce         produce --records records round robin across partitions,
+                  each with a CRC32 checksum header, after parsing them with
+                  the produce command's input reader
+  consume direct  consume the topic directly and verify record counts,
+                  per-partition order, and checksums
+  consume group   consume the topic as a new group, commit, verify the records,
+                  and verify the committed offsets
+  transactions    commit one transaction and abort another, then verify that
+                  a read_committed consumer sees only the committed records
+  delete group    delete the group created while consuming
+  delete topic    delete the topic
+
+Besides the cluster, this validates kcl's own plumbing: every client is built
+from kcl's config (and thus the same TLS, SASL, and other settings as every
+other command), the topic is created with the same request as admin topic
+create, records to produce are parsed with the same input format reader as
+the produce command, and consumed records are written with the same output
+formatter as the consume command and parsed back before being verified. The
+format used for this is:
+
+  ` + selfTestFormat + `
+
+Transactions and group consuming use the client directly rather than the
+transact and consume command code.
+
+The topic and group are named with --prefix followed by a timestamp and the
+process ID; the transactional ID used is the topic name with a -txn suffix.
+Transactional IDs cannot be deleted and instead expire on the broker.
+
+If a step that later steps depend on fails, the later steps are skipped. The
+group and topic are deleted even if earlier steps fail or the command is
+interrupted (best effort), unless --keep is used, in which case their names
+are printed so they can be inspected.
+
+Each step must complete within --step-timeout. This exits non-zero if any step
+fails.
+`,
+		Args: cobra.ExactArgs(0),
+		Run: func(_ *cobra.Command, _ []string) {
+			if t.partitions < 1 {
+				out.Die("--partitions must be at least 1")
+			}
+			if t.records < 1 {
+				out.Die("--records must be at least 1")
+			}
+			name := fmt.Sprintf("%s-%s-%d", prefix, time.Now().UTC().Format("20060102-150405"), os.Getpid())
+			t.topic, t.group, t.txnID = name, name, name+"-txn"
+			t.run()
+		},
+	}
+
+	cmd.Flags().StringVar(&prefix, "prefix", "kcl-selftest", "prefix for the topic, group, and transactional ID names")
+	cmd.Flags().Int32Var(&t.partitions, "partitions", 3, "number of partitions to create the topic with")
+	cmd.Flags().Int16Var(&t.replicas, "replication-factor", -1, "replication factor to create the topic with; -1 uses the broker default (Kafka 2.4.0+)")
+	cmd.Flags().IntVar(&t.records, "records", 1000, "number of records to produce and consume")
+	cmd.Flags().BoolVar(&t.keep, "keep", false, "do not delete the topic and group when done, for debugging")
+	cmd.Flags().DurationVar(&t.stepTimeout, "step-timeout", 30*time.Second, "how long each step can take before failing")
+
+	return cmd
+}
+
+// selfTestCRCHeader is the record header containing the hex CRC32 of the
+// record's value.
+const selfTestCRCHeader = "kcl-selftest-crc32"
+
+// selfTestTxnRecords is how many records each transaction produces.
+const selfTestTxnRecords = 10
+
+// selfTestFormat is the format records are written and parsed with when
+// round tripping them through kcl's format code.
+const selfTestFormat = `%K{b4}%k%V{b4}%v%H{b4}%h{%K{b4}%k%V{b4}%v}`
+
+type selfTest struct {
+	cl   *client.Client
+	base *kgo.Client
+	adm  *kadm.Client
+	ctx  context.Context
+
+	topic       string
+	group       string
+	txnID       string
+	partitions  int32
+	replicas    int16
+	records     int
+	keep        bool
+	stepTimeout time.Duration
+
+	write func([]byte, *kgo.Record, *kgo.FetchPartition) []byte
+
+	produced     map[int32][]int // partition => record sequence numbers, in order
+	topicCreated bool
+	groupCreated bool
+
+	steps  int
+	failed int
+}
+
+type selfTestStep struct {
+	name     string
+	fn       func(context.Context) (string, error)
+	required bool // whether later steps depend on this step
+}
+
+func (t *selfTest) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+	t.ctx = ctx
+
+	var err error
+	t.write, err = format.ParseWriteFormat(selfTestFormat, '%')
+	out.MaybeDie(err, "unable to parse self-test output format: %v", err)
+
+	t.cl.AddOpt(kgo.RecordPartitioner(kgo.ManualPartitioner()))
+	t.base = t.cl.Client()
+	t.adm = kadm.NewClient(t.base)
+
+	var skip string
+	for _, s := range []selfTestStep{
+		{"connect", t.connect, true},
+		{"create topic", t.createTopic, true},
+		{"produce", t.produce, true},
+		{"consume direct", t.consumeDirect, false},
+		{"consume group", t.consumeGroup, false},
+		{"transactions", t.transactions, false},
+	} {
+		if skip == "" && ctx.Err() != nil {
+			skip = "interrupted"
+		}
+		if skip != "" {
+			t.report("SKIP", s.name, 0, skip)
+			continue
+		}
+		if !t.step(s.name, s.fn) && s.required {
+			skip = s.name + " failed"
+		}
+	}
+
+	// Cleanup runs even if we were interrupted, so cleanup steps do
+	// not use our signal canceled context.
+	t.ctx = context.Background()
+	if t.keep {
+		if t.groupCreated {
+			t.report("KEEP", "group", 0, t.group)
+		}
+		if t.topicCreated {
+			t.report("KEEP", "topic", 0, t.topic)
+		}
+	} else {
+		if t.groupCreated {
+			t.step("delete group", t.deleteGroup)
+		}
+		if t.topicCreated {
+			t.step("delete topic", t.deleteTopic)
+		}
+	}
+
+	fmt.Println()
+	if t.failed > 0 {
+		out.Die("FAIL: %d of %d steps failed", t.failed, t.steps)
+	}
+	fmt.Printf("PASS: all %d steps passed\n", t.steps)
+}
+
+// step runs fn with the step timeout and reports whether it passed.
+func (t *selfTest) step(name string, fn func(context.Context) (string, error)) bool {
+	ctx, cancel := context.WithTimeout(t.ctx, t.stepTimeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := fn(ctx)
+	t.steps++
+	if err != nil {
+		t.failed++
+		t.report("FAIL", name, time.Since(start), err.Error())
+		return false
+	}
+	t.report("PASS", name, time.Since(start), detail)
+	return true
+}
+
+func (*selfTest) report(result, name string, took time.Duration, detail string) {
+	var tookStr string
+	if took > 0 {
+		tookStr = took.Round(time.Millisecond).String()
+	}
+	fmt.Printf("%-4s  %-14s  %8s  %s\n", result, name, tookStr, detail)
+}
+
+func (t *selfTest) connect(ctx context.Context) (string, error) {
+	brokers, err := t.adm.ListBrokers(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("found %d brokers", len(brokers)), nil
+}
+
+func (t *selfTest) createTopic(ctx context.Context) (string, error) {
+	req, err := topic.NewCreateRequest(t.cl, []string{t.topic}, t.partitions, t.replicas, nil)
+	if err != nil {
+		return "", err
+	}
+	// The topic may be created even if we time out waiting for the
+	// response, so we always try to delete it.
+	t.topicCreated = true
+	resp, err := req.RequestWith(ctx, t.base)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Topics) != 1 {
+		return "", fmt.Errorf("create topics returned %d topics when we asked for one", len(resp.Topics))
+	}
+	if err := kerr.ErrorForCode(resp.Topics[0].ErrorCode); err != nil {
+		return "", err
+	}
+
+	for {
+		md, err := t.adm.Metadata(ctx, t.topic)
+		if err == nil {
+			td := md.Topics[t.topic]
+			ready := td.Err == nil && len(td.Partitions) == int(t.partitions)
+			for _, p := range td.Partitions {
+				ready = ready && p.Err == nil && p.Leader >= 0
+			}
+			if ready {
+				return fmt.Sprintf("created %s with %d partitions", t.topic, t.partitions), nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("created %s, but timed out waiting for all partitions to have leaders", t.topic)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// selfTestValue returns the value for the record with sequence number seq.
+func selfTestValue(seq int) []byte {
+	return []byte(fmt.Sprintf("kcl self-test record %d %s", seq, strings.Repeat(strconv.Itoa(seq%10), 64)))
+}
+
+func (t *selfTest) produce(ctx context.Context) (string, error) {
+	t.produced = make(map[int32][]int)
+	rs := make([]*kgo.Record, 0, t.records)
+	for seq := 0; seq < t.records; seq++ {
+		partition := int32(seq) % t.partitions
+		value := selfTestValue(seq)
+		rs = append(rs, &kgo.Record{
+			Partition: partition,
+			Key:       []byte(strconv.Itoa(seq)),
+			Value:     value,
+			Headers: []kgo.RecordHeader{{
+				Key:   selfTestCRCHeader,
+				Value: []byte(strconv.FormatUint(uint64(crc32.ChecksumIEEE(value)), 16)),
+			}},
+		})
+		t.produced[partition] = append(t.produced[partition], seq)
+	}
+	// We produce what the produce command's input reader parses, and,
+	// like the produce command, set the topic and partition afterwards.
+	rs, err := t.roundTrip(rs)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range rs {
+		r.Topic = t.topic
+	}
+	if err := t.base.ProduceSync(ctx, rs...).FirstErr(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("produced %d records across %d partitions", t.records, t.partitions), nil
+}
+
+// roundTrip writes rs with kcl's output formatter and parses them back with
+// kcl's input reader, returning the parsed records. Only the key, value, and
+// headers are round tripped; the partition and offset are copied over.
+func (t *selfTest) roundTrip(rs []*kgo.Record) ([]*kgo.Record, error) {
+	var buf []byte
+	p := new(kgo.FetchPartition)
+	for _, r := range rs {
+		buf = t.write(buf, r, p)
+	}
+	reader, err := format.NewReader(selfTestFormat, '%', bufio.MaxScanTokenSize, bytes.NewReader(buf), false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse self-test input format: %v", err)
+	}
+	parsed := make([]*kgo.Record, 0, len(rs))
+	for {
+		r, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse formatted record %d: %v", len(parsed), err)
+		}
+		if len(parsed) == len(rs) {
+			return nil, fmt.Errorf("parsed more records than the %d formatted", len(rs))
+		}
+		r.Partition, r.Offset = rs[len(parsed)].Partition, rs[len(parsed)].Offset
+		parsed = append(parsed, r)
+	}
+	if len(parsed) != len(rs) {
+		return nil, fmt.Errorf("parsed %d of %d formatted records", len(parsed), len(rs))
+	}
+	return parsed, nil
+}
+
+// consumeAll polls until it has consumed n records.
+func consumeAll(ctx context.Context, cl *kgo.Client, n int) ([]*kgo.Record, error) {
+	var rs []*kgo.Record
+	for len(rs) < n {
+		fetches := cl.PollFetches(ctx)
+		if ctx.Err() != nil {
+			return rs, fmt.Errorf("consumed only %d of %d records before timing out", len(rs), n)
+		}
+		if errs := fetches.Errors(); len(errs) > 0 {
+			return rs, fmt.Errorf("unable to fetch partition %d: %v", errs[0].Partition, errs[0].Err)
+		}
+		rs = append(rs, fetches.Records()...)
+	}
+	return rs, nil
+}
+
+// verify checks that rs are exactly the produced records, in per-partition
+// order, with valid checksums.
+func (t *selfTest) verify(rs []*kgo.Record) error {
+	if len(rs) != t.records {
+		return fmt.Errorf("consumed %d records, expected %d", len(rs), t.records)
+	}
+	next := make(map[int32]int)
+	for _, r := range rs {
+		seq, err := strconv.Atoi(string(r.Key))
+		if err != nil {
+			return fmt.Errorf("partition %d offset %d: invalid key %q", r.Partition, r.Offset, r.Key)
+		}
+		expected, i := t.produced[r.Partition], next[r.Partition]
+		if i >= len(expected) || expected[i] != seq {
+			return fmt.Errorf("partition %d offset %d: record %d is out of order", r.Partition, r.Offset, seq)
+		}
+		next[r.Partition]++
+
+		var crc string
+		for _, h := range r.Headers {
+			if h.Key == selfTestCRCHeader {
+				crc = string(h.Value)
+			}
+		}
+		if crc != strconv.FormatUint(uint64(crc32.ChecksumIEEE(r.Value)), 16) {
+			return fmt.Errorf("partition %d offset %d: checksum mismatch", r.Partition, r.Offset)
+		}
+		if !bytes.Equal(r.Value, selfTestValue(seq)) {
+			return fmt.Errorf("partition %d offset %d: unexpected value for record %d", r.Partition, r.Offset, seq)
+		}
+	}
+	return nil
+}
+
+// verifyFormatted round trips consumed records through kcl's format code,
+// as the consume command would print them, and verifies the result.
+func (t *selfTest) verifyFormatted(rs []*kgo.Record) error {
+	formatted, err := t.roundTrip(rs)
+	if err != nil {
+		return err
+	}
+	return t.verify(formatted)
+}
+
+func (t *selfTest) consumeDirect(ctx context.Context) (string, error) {
+	offsets := make(map[int32]kgo.Offset)
+	for p := int32(0); p < t.partitions; p++ {
+		offsets[p] = kgo.NewOffset().AtStart()
+	}
+	cl, err := t.cl.ClientWithOpts(kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{t.topic: offsets}))
+	if err != nil {
+		return "", err
+	}
+	defer cl.Close()
+
+	rs, err := consumeAll(ctx, cl, t.records)
+	if err == nil {
+		err = t.verifyFormatted(rs)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("consumed and verified %d records", len(rs)), nil
+}
+
+func (t *selfTest) consumeGroup(ctx context.Context) (string, error) {
+	cl, err := t.cl.ClientWithOpts(
+		kgo.ConsumerGroup(t.group),
+		kgo.ConsumeTopics(t.topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.DisableAutoCommit(),
+	)
+	if err != nil {
+		return "", err
+	}
+	t.groupCreated = true
+
+	rs, err := consumeAll(ctx, cl, t.records)
+	if err == nil {
+		err = t.verifyFormatted(rs)
+	}
+	if err == nil {
+		err = cl.CommitUncommittedOffsets(ctx)
+	}
+	cl.Close() // leave the group so that it can be deleted
+	if err != nil {
+		return "", err
+	}
+
+	committed, err := t.adm.FetchOffsets(ctx, t.group)
+	if err == nil {
+		err = committed.Error()
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to fetch committed offsets: %v", err)
+	}
+	for p := int32(0); p < t.partitions; p++ {
+		o, _ := committed.Lookup(t.topic, p)
+		if expected := int64(len(t.produced[p])); o.At != expected {
+			return "", fmt.Errorf("partition %d: committed offset %d, expected %d", p, o.At, expected)
+		}
+	}
+	return fmt.Sprintf("consumed and verified %d records as group %s and committed", len(rs), t.group), nil
+}
+
+func (t *selfTest) transactions(ctx context.Context) (string, error) {
+	ends, err := t.adm.ListEndOffsets(ctx, t.topic)
+	if err == nil {
+		err = ends.Error()
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to list end offsets: %v", err)
+	}
+	start, _ := ends.Lookup(t.topic, 0)
+
+	txn, err := t.cl.ClientWithOpts(kgo.TransactionalID(t.txnID))
+	if err != nil {
+		return "", err
+	}
+	defer txn.Close()
+
+	produceTxn := func(kind string, commit kgo.TransactionEndTry) error {
+		if err := txn.BeginTransaction(); err != nil {
+			return err
+		}
+		rs := make([]*kgo.Record, 0, selfTestTxnRecords)
+		for i := 0; i < selfTestTxnRecords; i++ {
+			rs = append(rs, &kgo.Record{Topic: t.topic, Key: []byte(fmt.Sprintf("txn-%s-%d", kind, i))})
+		}
+		if err := txn.ProduceSync(ctx, rs...).FirstErr(); err != nil {
+			txn.EndTransaction(ctx, kgo.TryAbort)
+			return err
+		}
+		return txn.EndTransaction(ctx, commit)
+	}
+	if err := produceTxn("commit", kgo.TryCommit); err != nil {
+		return "", fmt.Errorf("unable to commit transaction: %v", err)
+	}
+	if err := produceTxn("abort", kgo.TryAbort); err != nil {
+		return "", fmt.Errorf("unable to abort transaction: %v", err)
+	}
+
+	// A non-transactional record after both transactions tells a
+	// read_committed consumer when it has read everything.
+	sentinel := []byte("txn-sentinel")
+	if err := t.base.ProduceSync(ctx, &kgo.Record{Topic: t.topic, Key: sentinel}).FirstErr(); err != nil {
+		return "", fmt.Errorf("unable to produce sentinel record: %v", err)
+	}
+
+	cl, err := t.cl.ClientWithOpts(
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{t.topic: {0: kgo.NewOffset().At(start.Offset)}}),
+		kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer cl.Close()
+
+	var keys []string
+	for len(keys) == 0 || keys[len(keys)-1] != string(sentinel) {
+		fetches := cl.PollFetches(ctx)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("read %d records before timing out waiting for the sentinel record", len(keys))
+		}
+		if errs := fetches.Errors(); len(errs) > 0 {
+			return "", fmt.Errorf("unable to fetch: %v", errs[0].Err)
+		}
+		fetches.EachRecord(func(r *kgo.Record) { keys = append(keys, string(r.Key)) })
+	}
+
+	var committed int
+	for _, key := range keys[:len(keys)-1] {
+		switch {
+		case strings.HasPrefix(key, "txn-abort-"):
+			return "", errors.New("read_committed consumer read a record from an aborted transaction")
+		case key != fmt.Sprintf("txn-commit-%d", committed):
+			return "", fmt.Errorf("read_committed consumer read unexpected record %q", key)
+		}
+		committed++
+	}
+	if committed != selfTestTxnRecords {
+		return "", fmt.Errorf("read_committed consumer read %d of %d committed records", committed, selfTestTxnRecords)
+	}
+	return fmt.Sprintf("read_committed saw %d committed and 0 aborted records", committed), nil
+}
+
+func (t *selfTest) deleteGroup(ctx context.Context) (string, error) {
+	resps, err := t.adm.DeleteGroups(ctx, t.group)
+	if err == nil {
+		err = resps.Error()
+	}
+	if errors.Is(err, kerr.GroupIDNotFound) {
+		return t.group + " was never created", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "deleted " + t.group, nil
+}
+
+func (t *selfTest) deleteTopic(ctx context.Context) (string, error) {
+	resps, err := t.adm.DeleteTopics(ctx, t.topic)
+	if err == nil {
+		err = resps.Error()
+	}
+	if errors.Is(err, kerr.UnknownTopicOrPartition) {
+		return t.topic + " was never created", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "deleted " + t.topic, nil
+}
@@ -0,0 +1,145 @@
+package produce
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// followPoll is how long a follower waits to read again after hitting EOF.
+const followPoll = 100 * time.Millisecond
+
+// follower wraps input for --stay-open. Rather than returning io.EOF when the
+// input temporarily ends, reads block until more input arrives. A follower
+// only returns io.EOF once its context is canceled or no input has arrived
+// for the idle duration (if positive).
+//
+// If the input is a regular file opened from a path, the path is checked on
+// EOF: if the file has been replaced (rotated), the new file is opened and
+// read from the start, and if the file has been truncated, it is read again
+// from the start. This is similar to tail -F.
+type follower struct {
+	ctx  context.Context
+	idle time.Duration
+
+	chunks  chan followChunk
+	pending []byte
+	done    bool
+}
+
+type followChunk struct {
+	buf []byte
+	err error
+}
+
+func newFollower(ctx context.Context, idle time.Duration, in *os.File, path string) *follower {
+	f := &follower{
+		ctx:    ctx,
+		idle:   idle,
+		chunks: make(chan followChunk),
+	}
+	// Reads happen in a goroutine so that a read blocked on a pipe
+	// with no writes does not prevent us from exiting on signal or
+	// idle timeout. The goroutine is leaked if we exit while it is
+	// blocked, which is fine since we are exiting.
+	go f.loop(in, path)
+	return f
+}
+
+func (f *follower) loop(in *os.File, path string) {
+	// If the file at path is rotated, next is the new file. We keep
+	// reading the old file until EOF before switching so that anything
+	// written to it just before the rotation is not lost.
+	var next *os.File
+	for {
+		buf := make([]byte, 32<<10)
+		n, err := in.Read(buf)
+		if n > 0 {
+			select {
+			case f.chunks <- followChunk{buf: buf[:n]}:
+			case <-f.ctx.Done():
+				return
+			}
+		}
+		switch {
+		case err == nil && n > 0:
+			continue
+		case err == nil, err == io.EOF:
+			if next != nil {
+				in.Close()
+				in, next = next, nil
+				continue
+			}
+			select {
+			case <-time.After(followPoll):
+			case <-f.ctx.Done():
+				return
+			}
+			if path != "" {
+				next = reopenIfRotated(in, path)
+			}
+		default:
+			select {
+			case f.chunks <- followChunk{err: err}:
+			case <-f.ctx.Done():
+			}
+			return
+		}
+	}
+}
+
+// reopenIfRotated returns a newly opened file for path if the file at path is
+// no longer in. If in was truncated, it is seeked to the start. Otherwise, or
+// on any error, this returns nil.
+func reopenIfRotated(in *os.File, path string) *os.File {
+	cur, err := in.Stat()
+	if err != nil || !cur.Mode().IsRegular() {
+		return nil
+	}
+	now, err := os.Stat(path)
+	if err != nil {
+		return nil // rotated away and not yet recreated
+	}
+	if !os.SameFile(cur, now) {
+		reopened, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		return reopened
+	}
+	if pos, err := in.Seek(0, io.SeekCurrent); err == nil && now.Size() < pos {
+		in.Seek(0, io.SeekStart)
+	}
+	return nil
+}
+
+func (f *follower) Read(p []byte) (int, error) {
+	if f.done {
+		return 0, io.EOF
+	}
+	if len(f.pending) == 0 {
+		var idle <-chan time.Time
+		if f.idle > 0 {
+			t := time.NewTimer(f.idle)
+			defer t.Stop()
+			idle = t.C
+		}
+		select {
+		case c := <-f.chunks:
+			if c.err != nil {
+				return 0, c.err
+			}
+			f.pending = c.buf
+		case <-idle:
+			f.done = true
+			return 0, io.EOF
+		case <-f.ctx.Done():
+			f.done = true
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
@@ -5,6 +5,9 @@ import (
 	"context"
 	"io"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"unicode/utf8"
 
 	"github.com/spf13/cobra"
@@ -18,6 +21,7 @@ import (
 func Command(cl *client.Client) *cobra.Command {
 	var (
 		informat      string
+		formatFile    string
 		maxBuf        int
 		verboseFormat string
 		compression   string
@@ -26,6 +30,9 @@ func Command(cl *client.Client) *cobra.Command {
 		retries       int
 		tombstone     bool
 		partition     int32
+		input         string
+		stayOpen      bool
+		idleExit      time.Duration
 	)
 
 	cmd := &cobra.Command{
@@ -127,6 +134,46 @@ To read a compact key, value, and single header, with each piece being 3 bytes:
   -f '%K{3}%V{3}%H{1}%k%v%h{%K{3}%k%V{3}%v}'
 
 
+FORMAT FILES
+
+Complex formats can be written to a file and loaded with --format-file rather
+than passed with -f. In a format file:
+  - lines beginning with # are comments and are stripped entirely
+  - a line ending in a backslash is joined with the next line (a line ending
+    in an escaped backslash, \\, is not)
+  - all other newlines are literally part of the format
+  - a single newline ending the file is dropped
+
+For example, input where each record is a "key: " line followed by a "value: "
+line could be read with a file containing:
+  # keys and values are on alternating lines; the final newline
+  # is written as an escape since the file's last newline is dropped
+  key: %k
+  value: %v\n
+
+Errors in a format file are reported with the file and line they occur on.
+
+
+STREAMING INPUT
+
+By default, records are read from stdin; --input reads from a file or named
+pipe instead. Producing stops once the input ends.
+
+When kcl is used as a long lived shipper, the input may end only temporarily:
+a writer to a named pipe may close and reopen it, or a file may still be
+appended to. With --stay-open, kcl keeps the producer open at the end of input
+and continues polling for new input, similar to tail -f. If --input is a
+regular file and the file is replaced (rotated) or truncated, kcl reopens it
+and reads it from the start, similar to tail -F. A record that is partially
+written when the input pauses is not parsed until the rest of it arrives.
+
+With --stay-open, kcl only stops reading on SIGINT or SIGTERM, or, with
+--idle-exit, once no new input has arrived for the idle duration. All records
+read so far are then flushed before quitting. If the input stops partway
+through a record, the partial record is reported as an error after the
+complete records before it are flushed.
+
+
 REMARKS
 
 Delimiters can be of arbitrary length, but must match exactly. When parsing
@@ -147,7 +194,42 @@ Unfortunately, with exact sizing, the format string is unavoidably noisy.
 				out.Die("invalid multi character escape character")
 			}
 
-			reader, err := format.NewReader(informat, escape, maxBuf, os.Stdin, tombstone)
+			var ff *format.File
+			if formatFile != "" {
+				var err error
+				ff, err = format.ReadFile(formatFile)
+				out.MaybeDie(err, "unable to read format file: %v", err)
+				informat = ff.Format
+			}
+
+			if idleExit != 0 && !stayOpen {
+				out.Die("--idle-exit requires --stay-open")
+			}
+			in := os.Stdin
+			if input != "" && input != "-" {
+				f, err := os.Open(input)
+				out.MaybeDie(err, "unable to open input: %v", err)
+				defer f.Close()
+				in = f
+			}
+			var inr io.Reader = in
+			if stayOpen {
+				ctx, cancel := context.WithCancel(context.Background())
+				sigs := make(chan os.Signal, 2)
+				signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+				go func() {
+					<-sigs
+					cancel()
+				}()
+				var path string
+				if in != os.Stdin {
+					path = input
+				}
+				inr = newFollower(ctx, idleExit, in, path)
+			}
+
+			reader, err := format.NewReader(informat, escape, maxBuf, inr, tombstone)
+			err = ff.Err(err)
 			out.MaybeDie(err, "unable to parse in format: %v", err)
 			if reader.ParsesTopic() && len(args) == 1 {
 				out.Die("cannot produce to a specific topic; the parse format specifies that it parses a topic")
@@ -202,11 +284,12 @@ Unfortunately, with exact sizing, the format string is unavoidably noisy.
 			}
 
 			p := &kgo.FetchPartition{}
+			var readErr error
 			for {
 				r, err := reader.Next()
 				if err != nil {
 					if err != io.EOF {
-						out.Die("final error: %v", err)
+						readErr = err
 					}
 					break
 				}
@@ -226,11 +309,15 @@ Unfortunately, with exact sizing, the format string is unavoidably noisy.
 				})
 			}
 
+			// Every complete record read before an error (such as
+			// input ending partway through a record) is still flushed.
 			cl.Client().Flush(context.Background())
+			out.MaybeDie(readErr, "final error: %v", readErr)
 		},
 	}
 
 	cmd.Flags().StringVarP(&informat, "format", "f", "%v\n", "record only delimiter")
+	cmd.Flags().StringVar(&formatFile, "format-file", "", "if non-empty, a file to read the input format from (see FORMAT FILES in the help)")
 	cmd.Flags().StringVarP(&verboseFormat, "verbose-format", "v", "", "if non-empty, what to write to stdout when a record is successfully produced")
 	cmd.Flags().IntVar(&maxBuf, "max-delim-buf", bufio.MaxScanTokenSize, "maximum input to buffer before a delimiter is required, if using delimiters")
 	cmd.Flags().StringVarP(&compression, "compression", "z", "snappy", "compression to use for producing batches (none, gzip, snappy, lz4, zstd)")
@@ -239,6 +326,10 @@ Unfortunately, with exact sizing, the format string is unavoidably noisy.
 	cmd.Flags().IntVar(&retries, "retries", -1, "number of times to retry producing if non-negative")
 	cmd.Flags().BoolVarP(&tombstone, "tombstone", "Z", false, "produce empty values as tombstones")
 	cmd.Flags().Int32VarP(&partition, "partition", "p", -1, "a specific partition to produce to, if non-negative")
+	cmd.Flags().StringVar(&input, "input", "", "if non-empty, a file or named pipe to read records from rather than stdin")
+	cmd.Flags().BoolVar(&stayOpen, "stay-open", false, "at the end of input, keep polling for more input until signaled rather than quitting (see STREAMING INPUT in the help)")
+	cmd.Flags().DurationVar(&idleExit, "idle-exit", 0, "with --stay-open, quit once no new input has arrived for this long; 0 waits forever")
+	cmd.MarkFlagsMutuallyExclusive("format", "format-file")
 
 	return cmd
 }
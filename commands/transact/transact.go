@@ -38,6 +38,13 @@ local executable file named mirror).
 
 Once all records are read, kcl begins a transaction, writes all records to
 Kafka, and finishes the transaction.
+
+Any format can instead be loaded from a file with the corresponding
+--write-format-file, --read-format-file, or --format-file flag. Format files
+follow the same rules as in the consume and produce commands: lines beginning
+with # are comments, a line ending in a backslash is joined with the next line,
+other newlines are part of the format, and a single newline ending the file is
+dropped.
 `
 
 func Command(cl *client.Client) *cobra.Command {
@@ -55,6 +62,10 @@ func Command(cl *client.Client) *cobra.Command {
 
 		rwFormat string
 
+		writeFormatFile string
+		readFormatFile  string
+		rwFormatFile    string
+
 		// Producing opts
 		readFormat  string
 		maxBuf      int
@@ -149,7 +160,8 @@ func Command(cl *client.Client) *cobra.Command {
 			defer func() { <-dead }()
 
 			if len(args) == 1 && args[0] == "mirror" {
-				if readFormat != "" || writeFormat != "" || rwFormat != "" {
+				if readFormat != "" || writeFormat != "" || rwFormat != "" ||
+					readFormatFile != "" || writeFormatFile != "" || rwFormatFile != "" {
 					out.Die("formats must not be specified when mirroring")
 				}
 				if len(destTopic) == 0 {
@@ -171,15 +183,32 @@ func Command(cl *client.Client) *cobra.Command {
 				out.Die("invalid multi character escape character")
 			}
 
+			readFile := func(path string, dst *string) *format.File {
+				if path == "" {
+					return nil
+				}
+				ff, err := format.ReadFile(path)
+				out.MaybeDie(err, "unable to read format file: %v", err)
+				*dst = ff.Format
+				return ff
+			}
+			wff := readFile(writeFormatFile, &writeFormat)
+			rff := readFile(readFormatFile, &readFormat)
+			if rwff := readFile(rwFormatFile, &rwFormat); rwff != nil {
+				wff, rff = rwff, rwff
+			}
+
 			if rwFormat != "" {
 				readFormat = rwFormat
 				writeFormat = rwFormat
 			}
 
 			w, err := format.ParseWriteFormat(writeFormat, escape)
+			err = wff.Err(err)
 			out.MaybeDie(err, "unable to parse write format: %v", err)
 
 			r, err := format.NewReader(readFormat, escape, maxBuf, nil, tombstone)
+			err = rff.Err(err)
 			out.MaybeDie(err, "unable to parse read format: %v", err)
 			if r.ParsesTopic() && len(destTopic) != 0 {
 				out.Die("cannot produce to a destination topic; the read format specifies that it parses a topic")
@@ -204,6 +233,12 @@ func Command(cl *client.Client) *cobra.Command {
 	cmd.Flags().StringVarP(&writeFormat, "write-format", "w", "", "format to write to the transform program")
 	cmd.Flags().StringVarP(&readFormat, "read-format", "r", "", "format to read from the transform program")
 	cmd.Flags().StringVar(&rwFormat, "rw", "", "if non-empty, the format to use for both reading and writing (overrides w and r)")
+	cmd.Flags().StringVar(&writeFormatFile, "write-format-file", "", "if non-empty, a file to read the write format from")
+	cmd.Flags().StringVar(&readFormatFile, "read-format-file", "", "if non-empty, a file to read the read format from")
+	cmd.Flags().StringVar(&rwFormatFile, "format-file", "", "if non-empty, a file to read the format to use for both reading and writing from (overrides w and r)")
+	cmd.MarkFlagsMutuallyExclusive("write-format", "write-format-file")
+	cmd.MarkFlagsMutuallyExclusive("read-format", "read-format-file")
+	cmd.MarkFlagsMutuallyExclusive("rw", "format-file")
 
 	cmd.Flags().IntVar(&maxBuf, "max-delim-buf", bufio.MaxScanTokenSize, "maximum input to buffer before a delimiter is required, if using delimiters")
 	cmd.Flags().StringVarP(&compression, "compression", "z", "snappy", "compression to use for producing batches (none, gzip, snappy, lz4, zstd)")
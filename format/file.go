@@ -0,0 +1,85 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// File is a format loaded from a file with ReadFile.
+type File struct {
+	Path   string
+	Format string
+
+	lines []int // source line for every byte in Format
+}
+
+// ReadFile reads a format from the file at path.
+//
+// Lines beginning with # are comments and are stripped entirely, including
+// their newline. A line ending in an odd number of backslashes is continued:
+// the final backslash and the newline are removed, joining the line with the
+// next. All other newlines are literally part of the format, with the
+// exception of a single newline ending the file, which is dropped.
+func ReadFile(path string) (*File, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{Path: path}
+	lines := strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+	for i, line := range lines {
+		lineno := i + 1
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		continued := (len(line)-len(strings.TrimRight(line, `\`)))%2 == 1
+		if continued {
+			if i == len(lines)-1 {
+				return nil, fmt.Errorf("%s:%d: line continuation at end of file", path, lineno)
+			}
+			line = line[:len(line)-1]
+		} else if i < len(lines)-1 {
+			line += "\n"
+		}
+		f.Format += line
+		for j := 0; j < len(line); j++ {
+			f.lines = append(f.lines, lineno)
+		}
+	}
+	if len(f.Format) == 0 {
+		return nil, fmt.Errorf("%s: file contains no format", path)
+	}
+	return f, nil
+}
+
+// Err annotates an error from parsing the file's format with the file path
+// and, if known, the line the error occurred on. This returns err unmodified
+// if f or err is nil.
+func (f *File) Err(err error) error {
+	if f == nil || err == nil {
+		return err
+	}
+	var oe *offsetError
+	if !errors.As(err, &oe) || len(f.lines) == 0 {
+		return fmt.Errorf("%s: %v", f.Path, err)
+	}
+	at := oe.at - 1
+	if at < 0 {
+		at = 0
+	} else if at >= len(f.lines) {
+		at = len(f.lines) - 1
+	}
+	return fmt.Errorf("%s:%d: %v", f.Path, f.lines[at], err)
+}
+
+// offsetError is a format parsing error that tracks how far into the format
+// parsing got before failing.
+type offsetError struct {
+	at  int
+	err error
+}
+
+func (e *offsetError) Error() string { return e.err.Error() }
+func (e *offsetError) Unwrap() error { return e.err }
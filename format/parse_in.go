@@ -34,6 +34,7 @@ func NewReader(infmt string, escape rune, maxBuf int, reader io.Reader, tombston
 	if err := r.parseReadFormat(infmt, escape, tombstone); err != nil {
 		return nil, err
 	}
+	r.SetReader(reader)
 	return r, nil
 }
 
@@ -41,6 +42,9 @@ func (r *Reader) ParsesTopic() bool {
 	return r.parsesTopic()
 }
 
+// Next returns the next record. This returns io.EOF if the input ends cleanly
+// between records, and an error wrapping io.ErrUnexpectedEOF if the input ends
+// partway through a record.
 func (r *Reader) Next() (*kgo.Record, error) {
 	r.on = new(kgo.Record)
 	err := r.fn(r)
@@ -53,6 +57,10 @@ func (r *Reader) SetReader(reader io.Reader) {
 		r.scanner = bufio.NewScanner(r.r)
 		r.scanner.Buffer(r.scanbuf, r.scanmax)
 		r.scanner.Split(r.delimiter.split)
+	} else {
+		// Sized parsing always reads through a bytePeekWrapper so
+		// that we can tell how far into a record we are at EOF.
+		r.r = &bytePeekWrapper{r: reader}
 	}
 }
 
@@ -68,7 +76,14 @@ func (p parseBits) parsesKey() bool     { return p&2 != 0 }
 func (p parseBits) parsesValue() bool   { return p&4 != 0 }
 func (p parseBits) parsesHeaders() bool { return p&8 != 0 }
 
-func (r *Reader) parseReadFormat(format string, escape rune, tombstone bool) error {
+func (r *Reader) parseReadFormat(format string, escape rune, tombstone bool) (err error) {
+	total := len(format)
+	defer func() {
+		if err != nil {
+			err = &offsetError{at: total - len(format), err: err}
+		}
+	}()
+
 	var (
 		// If we see any sized fields, we ensure that the size comes
 		// before the field with sawXyz. Additionally, we ensure that
@@ -273,6 +288,7 @@ func (r *Reader) parseReadFormat(format string, escape rune, tombstone bool) err
 					return errors.New("invalid header specification: internally uses delimiters, not sized fields")
 				}
 				sizeFns = append(sizeFns, func(r *Reader) error {
+					inr.r = r.r // the outer reader may have been replaced
 					for i := uint64(0); i < headersNum; i++ {
 						if err := inr.fn(inr); err != nil {
 							return err
@@ -312,14 +328,25 @@ func (r *Reader) parseReadFormat(format string, escape rune, tombstone bool) err
 		}
 
 		r.fn = func(r *Reader) error {
+			start := r.consumed()
 			for i, piece := range pieces {
+				var err error
 				if len(piece) > 0 {
-					if _, err := io.ReadFull(r.r, piece); err != nil {
-						return fmt.Errorf("unable to read piece: %v", err)
+					if _, err = io.ReadFull(r.r, piece); err != nil {
+						err = fmt.Errorf("unable to read piece: %w", err)
 					}
 				}
-				if err := sizeFns[i](r); err != nil {
-					return err
+				if err == nil {
+					err = sizeFns[i](r)
+				}
+				if err != nil {
+					if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+						return err
+					}
+					if consumed := r.consumed() - start; consumed > 0 {
+						return fmt.Errorf("%w: input ended mid-record (%d bytes read)", io.ErrUnexpectedEOF, consumed)
+					}
+					return io.EOF
 				}
 			}
 			return nil
@@ -366,6 +393,9 @@ func (r *Reader) parseReadFormat(format string, escape rune, tombstone bool) err
 			if r.scanner.Err() != nil {
 				return r.scanner.Err()
 			}
+			if scanned > 0 {
+				return fmt.Errorf("%w: input ended after %d of %d delimited fields in a record", io.ErrUnexpectedEOF, scanned, len(d.delims))
+			}
 			return io.EOF
 		}
 	}
@@ -524,6 +554,15 @@ type bytePeekWrapper struct {
 	haspeek bool
 	peek    byte
 	r       io.Reader
+	n       int64 // bytes consumed, excluding an unskipped peek
+}
+
+// consumed returns how many bytes have been consumed from a sized reader.
+func (r *Reader) consumed() int64 {
+	if b, ok := r.r.(*bytePeekWrapper); ok {
+		return b.n
+	}
+	return 0
 }
 
 func (b *bytePeekWrapper) Peek() (byte, error) {
@@ -541,7 +580,10 @@ func (b *bytePeekWrapper) Peek() (byte, error) {
 }
 
 func (b *bytePeekWrapper) SkipPeek() {
-	b.haspeek = false
+	if b.haspeek {
+		b.haspeek = false
+		b.n++
+	}
 }
 
 func (b *bytePeekWrapper) Read(p []byte) (n int, err error) {
@@ -554,5 +596,7 @@ func (b *bytePeekWrapper) Read(p []byte) (n int, err error) {
 		n++
 	}
 	nn, err := b.r.Read(p[n:])
-	return n + nn, err
+	n += nn
+	b.n += int64(n)
+	return n, err
 }
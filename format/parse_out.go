@@ -15,7 +15,14 @@ import (
 	"github.com/twmb/go-strftime"
 )
 
-func ParseWriteFormat(format string, escape rune) (func([]byte, *kgo.Record, *kgo.FetchPartition) []byte, error) {
+func ParseWriteFormat(format string, escape rune) (fn func([]byte, *kgo.Record, *kgo.FetchPartition) []byte, err error) {
+	total := len(format)
+	defer func() {
+		if err != nil {
+			err = &offsetError{at: total - len(format), err: err}
+		}
+	}()
+
 	var argFns []func([]byte, *kgo.Record, *kgo.FetchPartition) []byte
 	var pieces [][]byte
 	var piece []byte